@@ -0,0 +1,91 @@
+package chi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.rumenx.com/geolocation"
+)
+
+// Middleware returns a chi middleware configured with opts.
+// The Location and RequestInfo are stored with geolocation.NewContextKey, so the accessors
+// in this package and in the geolocation package can both read them.
+//
+// The chi route pattern (e.g. /users/{id}) is available to WithSkipRoutes and
+// WithRoutePolicy even when the middleware is registered with r.Use, before chi has routed the request.
+//
+// Example:
+//
+//	r := chi.NewRouter()
+//	r.Use(chiadapter.Middleware(
+//		geolocation.WithSkipRoutes("/health"),
+//		geolocation.WithRoutePolicy("/checkout/{id}", geolocation.WithErrorHandler(reject)),
+//	))
+func Middleware(opts ...geolocation.Option) func(http.Handler) http.Handler {
+	return geolocation.RouteMiddleware(RoutePattern, opts...)
+}
+
+// GeoInfoMiddleware adds the Location and a lazily computed GeoInfo to the request context.
+// opts selects which parts of GeoInfo are computed.
+func GeoInfoMiddleware(opts geolocation.GeoInfoOptions) func(http.Handler) http.Handler {
+	return Middleware(geolocation.WithGeoInfo(opts))
+}
+
+// GeoFenceMiddleware returns a middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+func GeoFenceMiddleware(fence *geolocation.GeoFence, opts ...geolocation.Option) func(http.Handler) http.Handler {
+	return Middleware(append([]geolocation.Option{geolocation.WithGeoFence(fence)}, opts...)...)
+}
+
+// RoutePattern returns the chi route pattern matching r, e.g. /users/{id}, or empty string
+// if r is not served by a chi router or no route matches.
+// Middleware registered with r.Use runs before chi has routed the request, so the pattern is
+// looked up in the routing tree; within r.With, r.Group and handlers it is read from the route context.
+func RoutePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	pattern := rctx.RoutePattern()
+	if pattern != "" && !strings.HasSuffix(pattern, "*") {
+		return pattern
+	}
+	if rctx.Routes == nil {
+		return pattern
+	}
+	path := r.URL.RawPath
+	if path == "" {
+		path = r.URL.Path
+	}
+	if found := rctx.Routes.Find(chi.NewRouteContext(), r.Method, path); found != "" {
+		return found
+	}
+	return pattern
+}
+
+// FromContext retrieves the geolocation info from the context.
+func FromContext(ctx context.Context) *geolocation.Location {
+	return geolocation.FromContext(ctx)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(ctx context.Context, key string) *geolocation.Location {
+	return geolocation.FromContextKey(ctx, key)
+}
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from the context.
+func RequestInfoFromContext(ctx context.Context) *geolocation.RequestInfo {
+	return geolocation.RequestInfoFromContext(ctx)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from the context, computing it on first access.
+func GeoInfoFromContext(ctx context.Context) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContext(ctx)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(ctx context.Context, key string) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContextKey(ctx, key)
+}
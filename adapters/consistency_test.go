@@ -0,0 +1,281 @@
+// Package adapters_test checks that every framework adapter produces identical output.
+package adapters_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ginframework "github.com/gin-gonic/gin"
+	chiframework "github.com/go-chi/chi/v5"
+	fiberframework "github.com/gofiber/fiber/v2"
+	gorillaframework "github.com/gorilla/mux"
+	echoframework "github.com/labstack/echo/v4"
+	"go.rumenx.com/geolocation"
+	chiadapter "go.rumenx.com/geolocation/adapters/chi"
+	echoadapter "go.rumenx.com/geolocation/adapters/echo"
+	fiberadapter "go.rumenx.com/geolocation/adapters/fiber"
+	ginadapter "go.rumenx.com/geolocation/adapters/gin"
+	gorillaadapter "go.rumenx.com/geolocation/adapters/gorilla"
+	httpadapter "go.rumenx.com/geolocation/adapters/nethttp"
+)
+
+var headerSets = map[string]map[string]string{
+	"cloudflare desktop": {
+		"CF-Connecting-IP":       "1.2.3.4",
+		"CF-IPCountry":           "BG",
+		"User-Agent":             "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+		"Accept-Language":        "bg-BG,bg;q=0.9,en;q=0.8",
+		"Sec-CH-DPR":             "1.5",
+		"Sec-CH-Viewport-Width":  "1280",
+		"Sec-CH-Viewport-Height": "720",
+	},
+	"in-app mobile with screen cookie": {
+		"CF-Connecting-IP": "2001:db8::1",
+		"CF-IPCountry":     "JP",
+		"User-Agent":       "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Safari Line/13.16.0",
+		"Accept-Language":  "ja",
+		"Cookie":           "other=1; geo_screen=w=390&h=844&dpr=3&vw=390&vh=664",
+	},
+	"missing headers": {},
+}
+
+func newRequest(headers map[string]string) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func encode(t *testing.T, info *geolocation.GeoInfo) string {
+	t.Helper()
+	if info == nil {
+		t.Fatal("GeoInfo not found in context")
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("failed to encode GeoInfo: %v", err)
+	}
+	return string(data)
+}
+
+func TestAdapters_SameOutputForSameHeaders(t *testing.T) {
+	ginframework.SetMode(ginframework.TestMode)
+
+	for name, headers := range headerSets {
+		t.Run(name, func(t *testing.T) {
+			want := encode(t, geolocation.GetGeoInfo(newRequest(headers)))
+			got := map[string]string{}
+
+			core := geolocation.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got["core"] = encode(t, geolocation.GeoInfoFromContext(r.Context()))
+			}))
+			core.ServeHTTP(httptest.NewRecorder(), newRequest(headers))
+
+			nethttp := httpadapter.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got["nethttp"] = encode(t, httpadapter.GeoInfoFromContext(r.Context()))
+			}))
+			nethttp.ServeHTTP(httptest.NewRecorder(), newRequest(headers))
+
+			g := ginframework.New()
+			g.Use(ginadapter.Middleware())
+			g.GET("/", func(c *ginframework.Context) {
+				got["gin"] = encode(t, ginadapter.GeoInfoFromContext(c))
+			})
+			g.ServeHTTP(httptest.NewRecorder(), newRequest(headers))
+
+			e := echoframework.New()
+			e.Use(echoadapter.Middleware())
+			e.GET("/", func(c echoframework.Context) error {
+				got["echo"] = encode(t, echoadapter.GeoInfoFromContext(c))
+				return nil
+			})
+			e.ServeHTTP(httptest.NewRecorder(), newRequest(headers))
+
+			app := fiberframework.New()
+			app.Use(fiberadapter.Middleware())
+			app.Get("/", func(c *fiberframework.Ctx) error {
+				got["fiber"] = encode(t, fiberadapter.GeoInfoFromContext(c))
+				return nil
+			})
+			resp, err := app.Test(newRequest(headers))
+			if err != nil {
+				t.Fatalf("fiber app test error: %v", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+
+			cr := chiframework.NewRouter()
+			cr.Use(chiadapter.Middleware())
+			cr.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				got["chi"] = encode(t, chiadapter.GeoInfoFromContext(r.Context()))
+			})
+			cr.ServeHTTP(httptest.NewRecorder(), newRequest(headers))
+
+			gr := gorillaframework.NewRouter()
+			gr.Use(gorillaadapter.Middleware())
+			gr.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				got["gorilla"] = encode(t, gorillaadapter.GeoInfoFromContext(r.Context()))
+			})
+			gr.ServeHTTP(httptest.NewRecorder(), newRequest(headers))
+
+			for _, adapter := range []string{"core", "nethttp", "gin", "echo", "fiber", "chi", "gorilla"} {
+				if got[adapter] != want {
+					t.Errorf("%s adapter output differs:\n got: %s\nwant: %s", adapter, got[adapter], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdapters_LanguageSwitcher(t *testing.T) {
+	ginframework.SetMode(ginframework.TestMode)
+	cfg := &geolocation.Config{DefaultLanguage: "en", CookieName: "site_lang"}
+	s, err := geolocation.NewLanguageSwitcher(cfg, "en", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Cookie = &http.Cookie{MaxAge: 60}
+	s.CSRF = func(req *geolocation.Request, token string) bool { return token == "secret" }
+
+	g := ginframework.New()
+	g.Any("/language", ginadapter.LanguageSwitcher(s))
+	e := echoframework.New()
+	e.Any("/language", echoadapter.LanguageSwitcher(s))
+	app := fiberframework.New()
+	app.All("/language", fiberadapter.LanguageSwitcher(s))
+
+	requests := map[string]func() *http.Request{
+		"get": func() *http.Request {
+			r := httptest.NewRequest("GET", "/language?lang=de&return_to=/page", nil)
+			r.Header.Set("Referer", "/other")
+			return r
+		},
+		"post form": func() *http.Request {
+			r := httptest.NewRequest("POST", "/language?return_to=/page", strings.NewReader("lang=de&csrf_token=secret"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return r
+		},
+		"post bad token": func() *http.Request {
+			r := httptest.NewRequest("POST", "/language", strings.NewReader("lang=de&csrf_token=wrong"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return r
+		},
+		"json": func() *http.Request {
+			r := httptest.NewRequest("GET", "/language?lang=en&return_to=https://evil.com/", nil)
+			r.Header.Set("Accept", "application/json")
+			return r
+		},
+		"unsupported": func() *http.Request {
+			return httptest.NewRequest("GET", "/language?lang=fr", nil)
+		},
+	}
+	describe := func(status int, header http.Header, body []byte) string {
+		return fmt.Sprintf("%d location=%q cookies=%q type=%q body=%s", status, header.Get("Location"),
+			header.Values("Set-Cookie"), header.Get("Content-Type"), body)
+	}
+	for name, newReq := range requests {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, newReq())
+			want := describe(w.Code, w.Header(), w.Body.Bytes())
+			got := map[string]string{}
+
+			for adapter, h := range map[string]http.Handler{"gin": g, "echo": e} {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, newReq())
+				got[adapter] = describe(w.Code, w.Header(), w.Body.Bytes())
+			}
+			resp, err := app.Test(newReq())
+			if err != nil {
+				t.Fatalf("fiber app test error: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			got["fiber"] = describe(resp.StatusCode, resp.Header, body)
+
+			for _, adapter := range []string{"gin", "echo", "fiber"} {
+				if got[adapter] != want {
+					t.Errorf("%s adapter output differs:\n got: %s\nwant: %s", adapter, got[adapter], want)
+				}
+			}
+		})
+	}
+}
+
+func TestAdapters_RoutePolicy(t *testing.T) {
+	ginframework.SetMode(ginframework.TestMode)
+	opts := func(health, users string) []geolocation.Option {
+		return []geolocation.Option{
+			geolocation.WithSkipRoutes(health),
+			geolocation.WithRoutePolicy(users, geolocation.WithFallbackCountry("DE")),
+		}
+	}
+	country := func(loc *geolocation.Location) string {
+		if loc == nil {
+			return "skipped"
+		}
+		return "country=" + loc.Country
+	}
+	want := map[string]string{"/health": "skipped", "/users/42": "country=DE", "/other": "country="}
+
+	for path, expected := range want {
+		t.Run(path, func(t *testing.T) {
+			got := map[string]string{}
+
+			mux := http.NewServeMux()
+			for _, p := range []string{"GET /health", "GET /users/{id}", "GET /other"} {
+				mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+					got["nethttp"] = country(httpadapter.FromContext(r.Context()))
+				})
+			}
+			httpadapter.ServeMux(mux, opts("GET /health", "GET /users/{id}")...).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+
+			g := ginframework.New()
+			g.Use(ginadapter.Middleware(opts("/health", "/users/:id")...))
+			e := echoframework.New()
+			e.Use(echoadapter.Middleware(opts("/health", "/users/:id")...))
+			app := fiberframework.New()
+			app.Use(fiberadapter.Middleware(opts("/health", "/users/:id")...))
+			cr := chiframework.NewRouter()
+			cr.Use(chiadapter.Middleware(opts("/health", "/users/{id}")...))
+			gr := gorillaframework.NewRouter()
+			gr.Use(gorillaadapter.Middleware(opts("/health", "/users/{id}")...))
+			for _, p := range []string{"/health", "/users/:id", "/other"} {
+				g.GET(p, func(c *ginframework.Context) { got["gin"] = country(ginadapter.FromContext(c)) })
+				e.GET(p, func(c echoframework.Context) error {
+					got["echo"] = country(echoadapter.FromContext(c))
+					return nil
+				})
+				app.Get(p, func(c *fiberframework.Ctx) error {
+					got["fiber"] = country(fiberadapter.FromContext(c))
+					return nil
+				})
+				p = strings.Replace(p, ":id", "{id}", 1)
+				cr.Get(p, func(w http.ResponseWriter, r *http.Request) {
+					got["chi"] = country(chiadapter.FromContext(r.Context()))
+				})
+				gr.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+					got["gorilla"] = country(gorillaadapter.FromContext(r.Context()))
+				})
+			}
+			for _, h := range []http.Handler{g, e, cr, gr} {
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+			}
+			resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+			if err != nil {
+				t.Fatalf("fiber app test error: %v", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+
+			for _, adapter := range []string{"nethttp", "gin", "echo", "fiber", "chi", "gorilla"} {
+				if got[adapter] != expected {
+					t.Errorf("%s adapter: got %q, want %q", adapter, got[adapter], expected)
+				}
+			}
+		})
+	}
+}
@@ -6,21 +6,100 @@ import (
 )
 
 // Middleware attaches geolocation info to Echo context.
-func Middleware() echo.MiddlewareFunc {
+// The full GeoInfo is available lazily through GeoInfoFromContext.
+// Without options it uses the defaults described in geolocation.NewMiddlewareOptions.
+//
+// Example:
+//
+//	e.Use(echoadapter.Middleware(
+//		geolocation.WithSkipPaths("/health"),
+//		geolocation.WithFallbackCountry("US"),
+//	))
+func Middleware(opts ...geolocation.Option) echo.MiddlewareFunc {
+	o := geolocation.NewMiddlewareOptions(opts...)
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			loc := geolocation.FromRequest(c.Request())
-			c.Set("geolocation", loc)
+			req := geolocation.NewRequest(c.Request())
+			req.Route = c.Path()
+			o := o.ForRoute(req.Route)
+			if o.Skip(req) {
+				return next(c)
+			}
+			info, err := o.RequestInfo(req)
+			if err = o.HandleError(err); err != nil {
+				return echo.NewHTTPError(geolocation.ErrorStatus(err), err.Error()).SetInternal(err)
+			}
+			if resp := o.CheckFence(req, info); resp != nil {
+				status, header, body := resp.Render()
+				for k := range header {
+					c.Response().Header().Set(k, header.Get(k))
+				}
+				return c.Blob(status, header.Get("Content-Type"), body)
+			}
+			c.Set(o.ContextKey, info.Location())
+			c.Set(o.InfoKey(), info)
 			return next(c)
 		}
 	}
 }
 
+// GeoInfoMiddleware attaches the Location and a lazily computed GeoInfo to Echo context.
+// opts selects which parts of GeoInfo are computed.
+func GeoInfoMiddleware(opts geolocation.GeoInfoOptions) echo.MiddlewareFunc {
+	return Middleware(geolocation.WithGeoInfo(opts))
+}
+
+// GeoFenceMiddleware returns a middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+func GeoFenceMiddleware(fence *geolocation.GeoFence, opts ...geolocation.Option) echo.MiddlewareFunc {
+	return Middleware(append([]geolocation.Option{geolocation.WithGeoFence(fence)}, opts...)...)
+}
+
 // FromContext retrieves the Location from Echo context.
 func FromContext(c echo.Context) *geolocation.Location {
-	loc := c.Get("geolocation")
+	return FromContextKey(c, geolocation.DefaultContextKey)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(c echo.Context, key string) *geolocation.Location {
+	loc := c.Get(key)
 	if l, ok := loc.(*geolocation.Location); ok {
 		return l
 	}
 	return nil
 }
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from Echo context.
+func RequestInfoFromContext(c echo.Context) *geolocation.RequestInfo {
+	return requestInfo(c, geolocation.DefaultContextKey)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from Echo context, computing it on first access.
+func GeoInfoFromContext(c echo.Context) *geolocation.GeoInfo {
+	return GeoInfoFromContextKey(c, geolocation.DefaultContextKey)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(c echo.Context, key string) *geolocation.GeoInfo {
+	if info := requestInfo(c, key); info != nil {
+		return info.GeoInfo()
+	}
+	return nil
+}
+
+func requestInfo(c echo.Context, key string) *geolocation.RequestInfo {
+	if i, ok := c.Get(key + "_info").(*geolocation.RequestInfo); ok {
+		return i
+	}
+	return nil
+}
+
+// LanguageSwitcher serves a geolocation.LanguageSwitcher as an Echo handler.
+//
+// Example:
+//
+//	switcher, err := geolocation.NewLanguageSwitcher(cfg, "en", "de", "fr")
+//	e.Any("/language", echoadapter.LanguageSwitcher(switcher))
+func LanguageSwitcher(s *geolocation.LanguageSwitcher) echo.HandlerFunc {
+	return echo.WrapHandler(s)
+}
@@ -76,3 +76,167 @@ func TestFromContext_Helper(t *testing.T) {
 		t.Error("expected to retrieve the set location value")
 	}
 }
+
+func TestMiddleware_GeoInfo(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware())
+	e.GET("/", func(c echo.Context) error {
+		info := GeoInfoFromContext(c)
+		if info == nil || info.CountryCode != "BG" || info.PreferredLanguage != "bg" {
+			t.Errorf("unexpected GeoInfo: %+v", info)
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg,en;q=0.8")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestGeoInfoMiddleware_Options(t *testing.T) {
+	e := echo.New()
+	e.Use(GeoInfoMiddleware(geolocation.GeoInfoOptions{}))
+	e.GET("/", func(c echo.Context) error {
+		if loc := FromContext(c); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected location, got %+v", loc)
+		}
+		info := GeoInfoFromContext(c)
+		if info == nil || info.CountryCode != "BG" || info.PreferredLanguage != "" {
+			t.Errorf("expected language part to be disabled, got %+v", info)
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoInfoFromContext_Nil(t *testing.T) {
+	c := echo.New().NewContext(nil, nil)
+	if GeoInfoFromContext(c) != nil || RequestInfoFromContext(c) != nil {
+		t.Error("expected nil when no value is set")
+	}
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(
+		geolocation.WithSkipPaths("/health"),
+		geolocation.WithContextKey("geo"),
+		geolocation.WithFallbackCountry("US"),
+	))
+	e.GET("/", func(c echo.Context) error {
+		if loc := FromContextKey(c, "geo"); loc == nil || loc.Country != "US" {
+			t.Errorf("expected fallback country under custom key, got %+v", loc)
+		}
+		if info := GeoInfoFromContextKey(c, "geo"); info == nil || info.CountryCode != "US" {
+			t.Errorf("expected GeoInfo under custom key, got %+v", info)
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/health", func(c echo.Context) error {
+		if FromContextKey(c, "geo") != nil {
+			t.Error("expected skipped path to have no location")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	for _, path := range []string{"/", "/health"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestMiddleware_ErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(geolocation.WithErrorHandler(func(err error) error {
+		return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+	})))
+	e.GET("/", func(c echo.Context) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestMiddleware_Routes(t *testing.T) {
+	e := echo.New()
+	e.Use(Middleware(
+		geolocation.WithSkipRoutes("/health"),
+		geolocation.WithRoutePolicy("/users/:id", geolocation.WithFallbackCountry("BG")),
+	))
+	e.GET("/users/:id", func(c echo.Context) error {
+		if loc := FromContext(c); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected route policy fallback BG, got %+v", loc)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/health", func(c echo.Context) error {
+		if FromContext(c) != nil {
+			t.Error("expected skipped route to have no location")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+	for _, path := range []string{"/users/1", "/health"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+}
+
+func TestGeoFenceMiddleware(t *testing.T) {
+	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+		Rules:    []geolocation.FenceRule{{Mode: geolocation.FenceDeny, Countries: []string{"KP"}}},
+		Response: geolocation.FenceResponse{Redirect: "/blocked"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.Use(GeoFenceMiddleware(fence))
+	e.GET("/", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	for country, status := range map[string]int{"DE": http.StatusOK, "KP": http.StatusFound} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("CF-IPCountry", country)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("%s: expected status %d, got %d", country, status, rec.Code)
+		}
+		if country == "KP" && rec.Header().Get("Location") != "/blocked" {
+			t.Errorf("expected redirect to /blocked, got %v", rec.Header())
+		}
+	}
+}
+
+func TestLanguageSwitcher(t *testing.T) {
+	s, err := geolocation.NewLanguageSwitcher(&geolocation.Config{DefaultLanguage: "en", CookieName: "lang"}, "en", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	e.GET("/language", LanguageSwitcher(s))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest("GET", "/language?lang=de&return_to=/de", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/de" || rec.Header().Get("Set-Cookie") != "lang=de; Path=/" {
+		t.Errorf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+}
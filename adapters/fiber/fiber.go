@@ -1,27 +1,233 @@
 package fiber
 
 import (
+	"net/url"
+	"sync"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 	"go.rumenx.com/geolocation"
 )
 
 // Middleware attaches geolocation info to Fiber context.
-func Middleware() fiber.Handler {
+// The full GeoInfo is available lazily through GeoInfoFromContext.
+// Without options it uses the defaults described in geolocation.NewMiddlewareOptions.
+//
+// The Fiber route path (e.g. /users/:id) is available to WithSkipRoutes, WithRoutePolicy and
+// geo fence paths even when the middleware is registered with app.Use, before Fiber has matched
+// the route. Looking it up scans the app's routes, so it is only done when one of them is used,
+// and the result is cached by method and path.
+//
+// Example:
+//
+//	app.Use(fiberadapter.Middleware(
+//		geolocation.WithSkipPaths("/health"),
+//		geolocation.WithFallbackCountry("US"),
+//	))
+func Middleware(opts ...geolocation.Option) fiber.Handler {
+	o := geolocation.NewMiddlewareOptions(opts...)
+	var routes *routeCache
+	if o.UsesRoutes() {
+		routes = &routeCache{}
+	}
 	return func(c *fiber.Ctx) error {
-		loc := &geolocation.Location{
-			IP:      c.Get("CF-Connecting-IP"),
-			Country: c.Get("CF-IPCountry"),
+		req := newRequest(c)
+		if routes != nil {
+			req.Route = routes.lookup(c)
+		}
+		o := o.ForRoute(req.Route)
+		if o.Skip(req) {
+			return c.Next()
+		}
+		info, err := o.RequestInfo(req)
+		if err = o.HandleError(err); err != nil {
+			return fiber.NewError(geolocation.ErrorStatus(err), err.Error())
 		}
-		c.Locals("geolocation", loc)
+		if resp := o.CheckFence(req, info); resp != nil {
+			status, header, body := resp.Render()
+			for k := range header {
+				c.Set(k, header.Get(k))
+			}
+			return c.Status(status).Send(body)
+		}
+		c.Locals(o.ContextKey, info.Location())
+		c.Locals(o.InfoKey(), info)
 		return c.Next()
 	}
 }
 
+// GeoInfoMiddleware attaches the Location and a lazily computed GeoInfo to Fiber context.
+// opts selects which parts of GeoInfo are computed.
+func GeoInfoMiddleware(opts geolocation.GeoInfoOptions) fiber.Handler {
+	return Middleware(geolocation.WithGeoInfo(opts))
+}
+
+// GeoFenceMiddleware returns a middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+func GeoFenceMiddleware(fence *geolocation.GeoFence, opts ...geolocation.Option) fiber.Handler {
+	return Middleware(append([]geolocation.Option{geolocation.WithGeoFence(fence)}, opts...)...)
+}
+
 // FromContext retrieves the Location from Fiber context.
 func FromContext(c *fiber.Ctx) *geolocation.Location {
-	loc := c.Locals("geolocation")
+	return FromContextKey(c, geolocation.DefaultContextKey)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(c *fiber.Ctx, key string) *geolocation.Location {
+	loc := c.Locals(key)
 	if l, ok := loc.(*geolocation.Location); ok {
 		return l
 	}
 	return nil
 }
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from Fiber context.
+func RequestInfoFromContext(c *fiber.Ctx) *geolocation.RequestInfo {
+	return requestInfo(c, geolocation.DefaultContextKey)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from Fiber context, computing it on first access.
+func GeoInfoFromContext(c *fiber.Ctx) *geolocation.GeoInfo {
+	return GeoInfoFromContextKey(c, geolocation.DefaultContextKey)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(c *fiber.Ctx, key string) *geolocation.GeoInfo {
+	if info := requestInfo(c, key); info != nil {
+		return info.GeoInfo()
+	}
+	return nil
+}
+
+func requestInfo(c *fiber.Ctx, key string) *geolocation.RequestInfo {
+	if i, ok := c.Locals(key + "_info").(*geolocation.RequestInfo); ok {
+		return i
+	}
+	return nil
+}
+
+// LanguageSwitcher serves a geolocation.LanguageSwitcher as a Fiber handler.
+// Parameters are read from the query string and, for POST requests, a URL-encoded or multipart form.
+//
+// Example:
+//
+//	switcher, err := geolocation.NewLanguageSwitcher(cfg, "en", "de", "fr")
+//	app.All("/language", fiberadapter.LanguageSwitcher(switcher))
+func LanguageSwitcher(s *geolocation.LanguageSwitcher) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		params := url.Values{}
+		c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+			params.Add(string(k), string(v))
+		})
+		c.Context().PostArgs().VisitAll(func(k, v []byte) {
+			params.Add(string(k), string(v))
+		})
+		if form, err := c.MultipartForm(); err == nil {
+			for k, vs := range form.Value {
+				params[k] = append(params[k], vs...)
+			}
+		}
+		status, header, body := s.Switch(newRequest(c), params)
+		for k, vs := range header {
+			for i, v := range vs {
+				if i == 0 {
+					c.Set(k, v)
+				} else {
+					c.Response().Header.Add(k, v)
+				}
+			}
+		}
+		return c.Status(status).Send(body)
+	}
+}
+
+// newRequest returns the framework-neutral view of the Fiber request.
+// Headers are read directly from fasthttp, so the view (and any RequestInfo built from it)
+// is only valid until the handler returns, like every other Fiber context value.
+func newRequest(c *fiber.Ctx) *geolocation.Request {
+	return &geolocation.Request{
+		Header:     &headers{h: &c.Request().Header},
+		Method:     c.Method(),
+		Host:       string(c.Request().Host()),
+		Path:       c.Path(),
+		RemoteAddr: c.Context().RemoteAddr().String(),
+	}
+}
+
+// RoutePath returns the path of the Fiber route handling c, e.g. /users/:id, or empty string
+// if no route matches. Middleware registered with app.Use runs under a prefix route before the
+// request is routed, so the path is then looked up in the app's routes.
+func RoutePath(c *fiber.Ctx) string {
+	cfg := c.App().Config()
+	if route := c.Route(); fiber.RoutePatternMatch(c.Path(), route.Path, cfg) {
+		return route.Path
+	}
+	for _, stack := range c.App().Stack() {
+		for _, route := range stack {
+			if route.Method == c.Method() && fiber.RoutePatternMatch(c.Path(), route.Path, cfg) {
+				return route.Path
+			}
+		}
+	}
+	return ""
+}
+
+// maxRouteCache bounds the route cache of a middleware. Paths come from clients, so the cache
+// is cleared rather than grown when it is full.
+const maxRouteCache = 4096
+
+// routeCache caches RoutePath results by method and path.
+type routeCache struct {
+	mu     sync.RWMutex
+	routes map[string]string
+}
+
+func (rc *routeCache) lookup(c *fiber.Ctx) string {
+	key := c.Method() + " " + c.Path() // A copy; fasthttp reuses the path buffer
+	rc.mu.RLock()
+	route, ok := rc.routes[key]
+	rc.mu.RUnlock()
+	if ok {
+		return route
+	}
+	route = RoutePath(c)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.routes) >= maxRouteCache {
+		clear(rc.routes)
+	}
+	if rc.routes == nil {
+		rc.routes = make(map[string]string)
+	}
+	rc.routes[key] = route
+	return route
+}
+
+// headers adapts fasthttp request headers to geolocation.Headers.
+// Values are copied because fasthttp reuses its buffers, and access is serialized because
+// fasthttp lookups share scratch space while RequestInfo parts may be computed concurrently.
+type headers struct {
+	mu sync.Mutex
+	h  *fasthttp.RequestHeader
+}
+
+func (h *headers) Get(key string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return string(h.h.Peek(key))
+}
+
+func (h *headers) Values(key string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	all := h.h.PeekAll(key)
+	if len(all) == 0 {
+		return nil
+	}
+	values := make([]string, len(all))
+	for i, v := range all {
+		values[i] = string(v)
+	}
+	return values
+}
@@ -1,11 +1,15 @@
 package fiber
 
 import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 	"go.rumenx.com/geolocation"
 )
 
@@ -104,3 +108,252 @@ func TestFromContext_Helper(t *testing.T) {
 		t.Errorf("unexpected error or status: %v, %d", err, resp.StatusCode)
 	}
 }
+
+func TestMiddleware_GeoInfo(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/", func(c *fiber.Ctx) error {
+		info := GeoInfoFromContext(c)
+		if info == nil || info.CountryCode != "BG" || info.PreferredLanguage != "bg" || info.Browser != "Firefox" {
+			t.Errorf("unexpected GeoInfo: %+v", info)
+		}
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg,en;q=0.8")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("fiber app test error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestGeoInfoMiddleware_Options(t *testing.T) {
+	app := fiber.New()
+	app.Use(GeoInfoMiddleware(geolocation.GeoInfoOptions{}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		if loc := FromContext(c); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected location, got %+v", loc)
+		}
+		info := GeoInfoFromContext(c)
+		if info == nil || info.CountryCode != "BG" || info.PreferredLanguage != "" {
+			t.Errorf("expected language part to be disabled, got %+v", info)
+		}
+		if RequestInfoFromContext(c) == nil {
+			t.Error("expected RequestInfo in context")
+		}
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg")
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("fiber app test error: %v", err)
+	}
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware(
+		geolocation.WithSkipPaths("/health"),
+		geolocation.WithContextKey("geo"),
+		geolocation.WithFallbackCountry("US"),
+	))
+	app.Get("/", func(c *fiber.Ctx) error {
+		if loc := FromContextKey(c, "geo"); loc == nil || loc.Country != "US" {
+			t.Errorf("expected fallback country under custom key, got %+v", loc)
+		}
+		if info := GeoInfoFromContextKey(c, "geo"); info == nil || info.CountryCode != "US" {
+			t.Errorf("expected GeoInfo under custom key, got %+v", info)
+		}
+		return c.SendString("ok")
+	})
+	app.Get("/health", func(c *fiber.Ctx) error {
+		if FromContextKey(c, "geo") != nil {
+			t.Error("expected skipped path to have no location")
+		}
+		return c.SendString("ok")
+	})
+
+	for _, path := range []string{"/", "/health"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatalf("fiber app test error: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestMiddleware_ErrorHandler(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware(geolocation.WithErrorHandler(func(err error) error {
+		return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+	})))
+	app.Get("/", func(c *fiber.Ctx) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatalf("fiber app test error: %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestGeoFenceMiddleware(t *testing.T) {
+	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+		Rules:    []geolocation.FenceRule{{Mode: geolocation.FenceAllow, Continents: []string{"EU"}}},
+		Response: geolocation.FenceResponse{HTML: "<h1>Europe only</h1>"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := fiber.New()
+	app.Use(GeoFenceMiddleware(fence))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+
+	for country, status := range map[string]int{"DE": http.StatusOK, "US": http.StatusForbidden} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("CF-IPCountry", country)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("fiber app test error: %v", err)
+		}
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected status %d, got %d", country, status, resp.StatusCode)
+		}
+		if country == "US" && resp.Header.Get("Content-Type") != "text/html; charset=utf-8" {
+			t.Errorf("expected HTML response, got %v", resp.Header)
+		}
+	}
+}
+
+func TestLanguageSwitcher(t *testing.T) {
+	s, err := geolocation.NewLanguageSwitcher(&geolocation.Config{DefaultLanguage: "en", CookieName: "lang"}, "en", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.PostOnly = true
+	app := fiber.New()
+	app.Post("/language", LanguageSwitcher(s))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("lang", "de")
+	form.WriteField("return_to", "/de")
+	form.Close()
+	req := httptest.NewRequest("POST", "/language", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("fiber app test error: %v", err)
+	}
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/de" || resp.Header.Get("Set-Cookie") != "lang=de; Path=/" {
+		t.Errorf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestRoutePath(t *testing.T) {
+	app := fiber.New()
+	var fromUse, fromHandler string
+	app.Use(func(c *fiber.Ctx) error {
+		fromUse = RoutePath(c)
+		return c.Next()
+	})
+	api := app.Group("/api")
+	api.Get("/users/:id", func(c *fiber.Ctx) error {
+		fromHandler = RoutePath(c)
+		return nil
+	})
+
+	tests := []struct{ path, route string }{
+		{"/api/users/42", "/api/users/:id"},
+		{"/missing", ""},
+	}
+	for _, tt := range tests {
+		fromUse, fromHandler = "", ""
+		if _, err := app.Test(httptest.NewRequest("GET", tt.path, nil)); err != nil {
+			t.Fatal(err)
+		}
+		if fromUse != tt.route {
+			t.Errorf("%s: RoutePath in app.Use = %q, want %q", tt.path, fromUse, tt.route)
+		}
+		if tt.route != "" && fromHandler != tt.route {
+			t.Errorf("%s: RoutePath in handler = %q, want %q", tt.path, fromHandler, tt.route)
+		}
+	}
+}
+
+// benchmarkMiddleware serves a request for the last of many routes through Middleware.
+func benchmarkMiddleware(b *testing.B, opts ...geolocation.Option) {
+	app := fiber.New()
+	app.Use(Middleware(opts...))
+	for i := range 200 {
+		app.Get(fmt.Sprintf("/r%d/:id", i), func(c *fiber.Ctx) error { return nil })
+	}
+	handler := app.Handler()
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI("/r199/42")
+	ctx.Request.Header.Set("CF-IPCountry", "DE")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler(ctx)
+	}
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	benchmarkMiddleware(b)
+}
+
+func BenchmarkMiddleware_SkipRoutes(b *testing.B) {
+	benchmarkMiddleware(b, geolocation.WithSkipRoutes("/health"))
+}
+
+func TestRouteCache(t *testing.T) {
+	app := fiber.New()
+	rc := &routeCache{}
+	var got string
+	app.Use(func(c *fiber.Ctx) error {
+		got = rc.lookup(c)
+		return c.Next()
+	})
+	app.Get("/users/:id", func(c *fiber.Ctx) error { return nil })
+	handler := app.Handler()
+	serve := func(path string) string {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod("GET")
+		ctx.Request.SetRequestURI(path)
+		handler(ctx)
+		return got
+	}
+
+	for range 2 {
+		if route := serve("/users/42"); route != "/users/:id" {
+			t.Errorf("route = %q, want /users/:id", route)
+		}
+	}
+	for i := range maxRouteCache + 10 {
+		serve(fmt.Sprintf("/users/%d", i))
+	}
+	if n := len(rc.routes); n > maxRouteCache {
+		t.Errorf("route cache grew to %d entries", n)
+	}
+	if route := serve("/missing"); route != "" {
+		t.Errorf("route = %q for a missing path", route)
+	}
+}
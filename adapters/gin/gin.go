@@ -8,19 +8,103 @@ import (
 type contextKey struct{}
 
 // Middleware attaches geolocation info to Gin context.
-func Middleware() gin.HandlerFunc {
+// The full GeoInfo is available lazily through GeoInfoFromContext.
+// Without options it uses the defaults described in geolocation.NewMiddlewareOptions.
+//
+// Example:
+//
+//	r.Use(ginadapter.Middleware(
+//		geolocation.WithSkipPaths("/health"),
+//		geolocation.WithFallbackCountry("US"),
+//	))
+func Middleware(opts ...geolocation.Option) gin.HandlerFunc {
+	o := geolocation.NewMiddlewareOptions(opts...)
 	return func(c *gin.Context) {
-		loc := geolocation.FromRequest(c.Request)
-		c.Set("geolocation", loc)
+		req := geolocation.NewRequest(c.Request)
+		req.Route = c.FullPath()
+		o := o.ForRoute(req.Route)
+		if o.Skip(req) {
+			c.Next()
+			return
+		}
+		info, err := o.RequestInfo(req)
+		if err = o.HandleError(err); err != nil {
+			c.AbortWithError(geolocation.ErrorStatus(err), err)
+			return
+		}
+		if resp := o.CheckFence(req, info); resp != nil {
+			status, header, body := resp.Render()
+			for k := range header {
+				c.Header(k, header.Get(k))
+			}
+			c.Data(status, header.Get("Content-Type"), body)
+			c.Abort()
+			return
+		}
+		c.Set(o.ContextKey, info.Location())
+		c.Set(o.InfoKey(), info)
 		c.Next()
 	}
 }
 
+// GeoInfoMiddleware attaches the Location and a lazily computed GeoInfo to Gin context.
+// opts selects which parts of GeoInfo are computed.
+func GeoInfoMiddleware(opts geolocation.GeoInfoOptions) gin.HandlerFunc {
+	return Middleware(geolocation.WithGeoInfo(opts))
+}
+
+// GeoFenceMiddleware returns a middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+func GeoFenceMiddleware(fence *geolocation.GeoFence, opts ...geolocation.Option) gin.HandlerFunc {
+	return Middleware(append([]geolocation.Option{geolocation.WithGeoFence(fence)}, opts...)...)
+}
+
 // FromContext retrieves the Location from Gin context.
 func FromContext(c *gin.Context) *geolocation.Location {
-	loc, _ := c.Get("geolocation")
+	return FromContextKey(c, geolocation.DefaultContextKey)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(c *gin.Context, key string) *geolocation.Location {
+	loc, _ := c.Get(key)
 	if l, ok := loc.(*geolocation.Location); ok {
 		return l
 	}
 	return nil
 }
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from Gin context.
+func RequestInfoFromContext(c *gin.Context) *geolocation.RequestInfo {
+	return requestInfo(c, geolocation.DefaultContextKey)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from Gin context, computing it on first access.
+func GeoInfoFromContext(c *gin.Context) *geolocation.GeoInfo {
+	return GeoInfoFromContextKey(c, geolocation.DefaultContextKey)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(c *gin.Context, key string) *geolocation.GeoInfo {
+	if info := requestInfo(c, key); info != nil {
+		return info.GeoInfo()
+	}
+	return nil
+}
+
+func requestInfo(c *gin.Context, key string) *geolocation.RequestInfo {
+	info, _ := c.Get(key + "_info")
+	if i, ok := info.(*geolocation.RequestInfo); ok {
+		return i
+	}
+	return nil
+}
+
+// LanguageSwitcher serves a geolocation.LanguageSwitcher as a Gin handler.
+//
+// Example:
+//
+//	switcher, err := geolocation.NewLanguageSwitcher(cfg, "en", "de", "fr")
+//	r.Any("/language", ginadapter.LanguageSwitcher(switcher))
+func LanguageSwitcher(s *geolocation.LanguageSwitcher) gin.HandlerFunc {
+	return gin.WrapH(s)
+}
@@ -75,3 +75,167 @@ func TestFromContext(t *testing.T) {
 		t.Error("expected to retrieve the set location value")
 	}
 }
+
+func TestMiddleware_GeoInfo(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/", func(c *gin.Context) {
+		info := GeoInfoFromContext(c)
+		if info == nil || info.CountryCode != "BG" || info.PreferredLanguage != "bg" {
+			t.Errorf("unexpected GeoInfo: %+v", info)
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg,en;q=0.8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestGeoInfoMiddleware_Options(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GeoInfoMiddleware(geolocation.GeoInfoOptions{}))
+	r.GET("/", func(c *gin.Context) {
+		if loc := FromContext(c); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected location, got %+v", loc)
+		}
+		info := GeoInfoFromContext(c)
+		if info == nil || info.CountryCode != "BG" || info.PreferredLanguage != "" {
+			t.Errorf("expected language part to be disabled, got %+v", info)
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoInfoFromContext_Nil(t *testing.T) {
+	c := &gin.Context{}
+	if GeoInfoFromContext(c) != nil || RequestInfoFromContext(c) != nil {
+		t.Error("expected nil when no value is set")
+	}
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(
+		geolocation.WithSkipPaths("/health"),
+		geolocation.WithContextKey("geo"),
+		geolocation.WithFallbackCountry("US"),
+	))
+	r.GET("/", func(c *gin.Context) {
+		if loc := FromContextKey(c, "geo"); loc == nil || loc.Country != "US" {
+			t.Errorf("expected fallback country under custom key, got %+v", loc)
+		}
+		if info := GeoInfoFromContextKey(c, "geo"); info == nil || info.CountryCode != "US" {
+			t.Errorf("expected GeoInfo under custom key, got %+v", info)
+		}
+	})
+	r.GET("/health", func(c *gin.Context) {
+		if FromContextKey(c, "geo") != nil {
+			t.Error("expected skipped path to have no location")
+		}
+	})
+
+	for _, path := range []string{"/", "/health"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestMiddleware_ErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(geolocation.WithErrorHandler(func(err error) error {
+		return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+	})))
+	r.GET("/", func(c *gin.Context) {
+		t.Error("handler should not be called")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+}
+
+func TestMiddleware_Routes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(
+		geolocation.WithSkipRoutes("/health"),
+		geolocation.WithRoutePolicy("/users/:id", geolocation.WithFallbackCountry("BG")),
+	))
+	r.GET("/users/:id", func(c *gin.Context) {
+		if loc := FromContext(c); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected route policy fallback BG, got %+v", loc)
+		}
+	})
+	r.GET("/health", func(c *gin.Context) {
+		if FromContext(c) != nil {
+			t.Error("expected skipped route to have no location")
+		}
+	})
+	for _, path := range []string{"/users/1", "/health"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+}
+
+func TestGeoFenceMiddleware(t *testing.T) {
+	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+		Rules:    []geolocation.FenceRule{{Mode: geolocation.FenceDeny, Countries: []string{"KP"}}},
+		Response: geolocation.FenceResponse{Status: http.StatusUnavailableForLegalReasons, JSON: map[string]string{"error": "blocked"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(GeoFenceMiddleware(fence))
+	r.GET("/", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for country, status := range map[string]int{"DE": http.StatusOK, "KP": http.StatusUnavailableForLegalReasons} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("CF-IPCountry", country)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d", country, status, w.Code)
+		}
+		if country == "KP" && (w.Body.String() != `{"error":"blocked"}` || w.Header().Get("Content-Type") != "application/json") {
+			t.Errorf("unexpected blocked response: %v %q", w.Header(), w.Body.String())
+		}
+	}
+}
+
+func TestLanguageSwitcher(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s, err := geolocation.NewLanguageSwitcher(&geolocation.Config{DefaultLanguage: "en", CookieName: "lang"}, "en", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/language", LanguageSwitcher(s))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/language?lang=de&return_to=/de", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/de" || w.Header().Get("Set-Cookie") != "lang=de; Path=/" {
+		t.Errorf("unexpected response %d %v", w.Code, w.Header())
+	}
+}
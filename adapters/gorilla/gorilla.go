@@ -0,0 +1,78 @@
+package gorilla
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.rumenx.com/geolocation"
+)
+
+// Middleware returns a gorilla/mux middleware configured with opts.
+// The Location and RequestInfo are stored with geolocation.NewContextKey, so the accessors
+// in this package and in the geolocation package can both read them.
+//
+// gorilla/mux runs middleware after matching, so the route's path template (e.g. /users/{id})
+// is available to WithSkipRoutes and WithRoutePolicy.
+//
+// Example:
+//
+//	r := mux.NewRouter()
+//	r.Use(gorillaadapter.Middleware(
+//		geolocation.WithSkipRoutes("/health"),
+//		geolocation.WithRoutePolicy("/checkout/{id}", geolocation.WithErrorHandler(reject)),
+//	))
+func Middleware(opts ...geolocation.Option) mux.MiddlewareFunc {
+	return geolocation.RouteMiddleware(RouteTemplate, opts...)
+}
+
+// GeoInfoMiddleware adds the Location and a lazily computed GeoInfo to the request context.
+// opts selects which parts of GeoInfo are computed.
+func GeoInfoMiddleware(opts geolocation.GeoInfoOptions) mux.MiddlewareFunc {
+	return Middleware(geolocation.WithGeoInfo(opts))
+}
+
+// GeoFenceMiddleware returns a middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+func GeoFenceMiddleware(fence *geolocation.GeoFence, opts ...geolocation.Option) mux.MiddlewareFunc {
+	return Middleware(append([]geolocation.Option{geolocation.WithGeoFence(fence)}, opts...)...)
+}
+
+// RouteTemplate returns the path template of the route matching r, e.g. /users/{id},
+// or empty string if r was not matched by a gorilla/mux router.
+func RouteTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return tpl
+}
+
+// FromContext retrieves the geolocation info from the context.
+func FromContext(ctx context.Context) *geolocation.Location {
+	return geolocation.FromContext(ctx)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(ctx context.Context, key string) *geolocation.Location {
+	return geolocation.FromContextKey(ctx, key)
+}
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from the context.
+func RequestInfoFromContext(ctx context.Context) *geolocation.RequestInfo {
+	return geolocation.RequestInfoFromContext(ctx)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from the context, computing it on first access.
+func GeoInfoFromContext(ctx context.Context) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContext(ctx)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(ctx context.Context, key string) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContextKey(ctx, key)
+}
@@ -0,0 +1,143 @@
+package gorilla
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.rumenx.com/geolocation"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Middleware())
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		loc := FromContext(r.Context())
+		if loc == nil || loc.IP != "1.2.3.4" || loc.Country != "BG" {
+			t.Errorf("expected IP 1.2.3.4 and country BG, got %+v", loc)
+		}
+		if geolocation.FromContext(r.Context()) != loc {
+			t.Error("expected the location to be shared with the geolocation package")
+		}
+		if info := GeoInfoFromContext(r.Context()); info == nil || info.PreferredLanguage != "bg" {
+			t.Errorf("unexpected GeoInfo: %+v", info)
+		}
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-Connecting-IP", "1.2.3.4")
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg,en;q=0.8")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestRouteTemplate(t *testing.T) {
+	var got string
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	api.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		got = RouteTemplate(r)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/items/1", nil))
+	if got != "/api/items/{id}" {
+		t.Errorf("expected /api/items/{id}, got %q", got)
+	}
+	if tpl := RouteTemplate(httptest.NewRequest("GET", "/", nil)); tpl != "" {
+		t.Errorf("expected empty template outside gorilla/mux, got %q", tpl)
+	}
+}
+
+func TestMiddleware_Routes(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Middleware(
+		geolocation.WithSkipRoutes("/health"),
+		geolocation.WithRoutePolicy("/checkout/{id}", geolocation.WithErrorHandler(func(err error) error {
+			return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+		})),
+		geolocation.WithRoutePolicy("/users/{id}", geolocation.WithContextKey("geo")),
+	))
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if FromContext(r.Context()) != nil {
+			t.Error("expected skipped route to have no location")
+		}
+	})
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if FromContextKey(r.Context(), "geo") == nil || GeoInfoFromContextKey(r.Context(), "geo") == nil {
+			t.Error("expected location under the route's context key")
+		}
+	})
+	r.HandleFunc("/checkout/{id}", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	})
+
+	cases := []struct {
+		path   string
+		status int
+	}{
+		{"/health", http.StatusOK},
+		{"/users/1", http.StatusOK},
+		{"/checkout/1", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest("GET", c.path, nil))
+		if rec.Code != c.status {
+			t.Errorf("%s: expected status %d, got %d", c.path, c.status, rec.Code)
+		}
+	}
+}
+
+func TestGeoInfoMiddleware_Options(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(GeoInfoMiddleware(geolocation.GeoInfoOptions{Language: true}))
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		info := GeoInfoFromContext(r.Context())
+		if info == nil || info.PreferredLanguage != "de" || info.Browser != "" {
+			t.Errorf("expected only language to be parsed, got %+v", info)
+		}
+		if RequestInfoFromContext(r.Context()) == nil {
+			t.Error("expected RequestInfo in context")
+		}
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Language", "de")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoFenceMiddleware(t *testing.T) {
+	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+		Rules: []geolocation.FenceRule{{Paths: []string{"/checkout/{id}"}, Mode: geolocation.FenceDeny, Countries: []string{"KP"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r := mux.NewRouter()
+	r.Use(GeoFenceMiddleware(fence))
+	r.HandleFunc("/", ok)
+	r.HandleFunc("/checkout/{id}", ok)
+
+	cases := []struct {
+		path, country string
+		status        int
+	}{
+		{"/", "KP", http.StatusOK},
+		{"/checkout/1", "DE", http.StatusOK},
+		{"/checkout/1", "KP", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", c.path, nil)
+		req.Header.Set("CF-IPCountry", c.country)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != c.status {
+			t.Errorf("%s from %s: expected status %d, got %d", c.path, c.country, c.status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"go.rumenx.com/geolocation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a unary interceptor configured with opts.
+// The Location and RequestInfo are read from incoming metadata (e.g. CF headers forwarded by a proxy),
+// with the peer address as the IP when CF-Connecting-IP is missing, and stored with
+// geolocation.NewContextKey so that FromContext in this package and in geolocation can read them.
+//
+// Route-based options match the full method name, e.g. "/pkg.Service/Method".
+//
+// Example:
+//
+//	srv := grpc.NewServer(
+//		grpc.UnaryInterceptor(grpcadapter.UnaryServerInterceptor(
+//			geolocation.WithSkipRoutes("/grpc.health.v1.Health/Check"),
+//		)),
+//		grpc.StreamInterceptor(grpcadapter.StreamServerInterceptor()),
+//	)
+func UnaryServerInterceptor(opts ...geolocation.Option) grpc.UnaryServerInterceptor {
+	o := geolocation.NewMiddlewareOptions(opts...)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := newContext(ctx, o, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream interceptor configured with opts.
+// See UnaryServerInterceptor.
+func StreamServerInterceptor(opts ...geolocation.Option) grpc.StreamServerInterceptor {
+	o := geolocation.NewMiddlewareOptions(opts...)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := newContext(ss.Context(), o, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// NewRequest returns the framework-neutral view of an incoming gRPC call.
+// Method is always POST, Path and Route are the full method name and Host is the :authority.
+func NewRequest(ctx context.Context, fullMethod string) *geolocation.Request {
+	md, _ := metadata.FromIncomingContext(ctx)
+	h := headers{md: md}
+	req := &geolocation.Request{
+		Header: h,
+		Method: http.MethodPost,
+		Host:   h.Get(":authority"),
+		Path:   fullMethod,
+		Route:  fullMethod,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+		if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+			h.peerIP = host
+			req.Header = h
+		}
+	}
+	return req
+}
+
+// FromContext retrieves the geolocation info from the context.
+func FromContext(ctx context.Context) *geolocation.Location {
+	return geolocation.FromContext(ctx)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(ctx context.Context, key string) *geolocation.Location {
+	return geolocation.FromContextKey(ctx, key)
+}
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from the context.
+func RequestInfoFromContext(ctx context.Context) *geolocation.RequestInfo {
+	return geolocation.RequestInfoFromContext(ctx)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from the context, computing it on first access.
+func GeoInfoFromContext(ctx context.Context) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContext(ctx)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(ctx context.Context, key string) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContextKey(ctx, key)
+}
+
+// newContext runs the middleware pipeline for a call and returns the context for the handler.
+func newContext(ctx context.Context, o *geolocation.MiddlewareOptions, fullMethod string) (context.Context, error) {
+	req := NewRequest(ctx, fullMethod)
+	o = o.ForRoute(req.Route)
+	if o.Skip(req) {
+		return ctx, nil
+	}
+	info, err := o.RequestInfo(req)
+	if err = o.HandleError(err); err != nil {
+		return nil, statusError(err)
+	}
+	if resp := o.CheckFence(req, info); resp != nil {
+		code, _, _ := resp.Render()
+		if code < http.StatusBadRequest { // redirects have no gRPC equivalent
+			code = http.StatusForbidden
+		}
+		return nil, status.Error(httpCode(code), http.StatusText(code))
+	}
+	return geolocation.NewContextKey(ctx, o.ContextKey, info), nil
+}
+
+// statusError converts a middleware error into a gRPC status error. Errors that already carry a
+// gRPC status are returned unchanged; otherwise the code is derived from geolocation.ErrorStatus.
+func statusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(httpCode(geolocation.ErrorStatus(err)), err.Error())
+}
+
+// httpCode maps an HTTP status code to the closest gRPC code.
+func httpCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden, http.StatusUnavailableForLegalReasons:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// headers adapts incoming gRPC metadata to geolocation.Headers.
+// Metadata keys are lowercase, and md.Get lowercases the key, so lookups are case-insensitive.
+// peerIP is used as CF-Connecting-IP when the metadata does not carry one.
+type headers struct {
+	md     metadata.MD
+	peerIP string
+}
+
+func (h headers) Get(key string) string {
+	if v := h.Values(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (h headers) Values(key string) []string {
+	v := h.md.Get(key)
+	if len(v) == 0 && h.peerIP != "" && http.CanonicalHeaderKey(key) == "Cf-Connecting-Ip" {
+		return []string{h.peerIP}
+	}
+	return v
+}
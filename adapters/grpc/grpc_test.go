@@ -0,0 +1,226 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.rumenx.com/geolocation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// geoService is a hand-written service descriptor, so the tests need no generated code.
+// Country returns the country from the handler context; Watch streams it once.
+var geoService = grpc.ServiceDesc{
+	ServiceName: "test.Geo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Country",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req any) (any, error) {
+				return wrapperspb.String(describe(ctx)), nil
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{FullMethod: "/test.Geo/Country"}, handler)
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Watch",
+		ServerStreams: true,
+		Handler: func(srv any, stream grpc.ServerStream) error {
+			return stream.SendMsg(wrapperspb.String(describe(stream.Context())))
+		},
+	}},
+}
+
+// describe summarizes the geolocation values found in ctx.
+func describe(ctx context.Context) string {
+	loc := FromContext(ctx)
+	if loc == nil {
+		return "none"
+	}
+	info := GeoInfoFromContext(ctx)
+	return loc.Country + "|" + loc.IP + "|" + info.PreferredLanguage + "|" + info.Browser
+}
+
+func newClient(t *testing.T, opts ...geolocation.Option) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(opts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(opts...)),
+	)
+	srv.RegisterService(&geoService, struct{}{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func cloudflareContext() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(),
+		"CF-Connecting-IP", "1.2.3.4",
+		"CF-IPCountry", "BG",
+		"Accept-Language", "bg,en;q=0.8",
+	)
+}
+
+func callCountry(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+	out := new(wrapperspb.StringValue)
+	err := conn.Invoke(ctx, "/test.Geo/Country", wrapperspb.String(""), out)
+	return out.GetValue(), err
+}
+
+func callWatch(ctx context.Context, conn *grpc.ClientConn) (string, error) {
+	stream, err := conn.NewStream(ctx, &geoService.Streams[0], "/test.Geo/Watch")
+	if err != nil {
+		return "", err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return "", err
+	}
+	out := new(wrapperspb.StringValue)
+	err = stream.RecvMsg(out)
+	return out.GetValue(), err
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	conn := newClient(t)
+	got, err := callCountry(cloudflareContext(), conn)
+	if err != nil || got != "BG|1.2.3.4|bg|grpc-go" {
+		t.Errorf("unexpected result %q (err %v)", got, err)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	conn := newClient(t)
+	got, err := callWatch(cloudflareContext(), conn)
+	if err != nil || got != "BG|1.2.3.4|bg|grpc-go" {
+		t.Errorf("unexpected result %q (err %v)", got, err)
+	}
+}
+
+func TestInterceptors_Options(t *testing.T) {
+	conn := newClient(t,
+		geolocation.WithSkipRoutes("/test.Geo/Watch"),
+		geolocation.WithErrorHandler(func(err error) error {
+			return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+		}),
+	)
+
+	_, err := callCountry(context.Background(), conn)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied without a country, got %v", err)
+	}
+	if got, err := callWatch(context.Background(), conn); err != nil || got != "none" {
+		t.Errorf("expected skipped method to have no location, got %q (err %v)", got, err)
+	}
+}
+
+func TestUnaryServerInterceptor_PeerAddress(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("cf-ipcountry", "DE"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("5.6.7.8"), Port: 4242}})
+
+	interceptor := UnaryServerInterceptor()
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Geo/Country"}, func(ctx context.Context, req any) (any, error) {
+		if loc := FromContext(ctx); loc == nil || loc.Country != "DE" || loc.IP != "5.6.7.8" {
+			t.Errorf("expected DE with peer IP 5.6.7.8, got %+v", loc)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		":authority", "geo.example.com",
+		"cf-connecting-ip", "1.2.3.4",
+		"user-agent", "grpc-go/1.84.0",
+	))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1}})
+
+	req := NewRequest(ctx, "/test.Geo/Country")
+	if req.Method != http.MethodPost || req.Host != "geo.example.com" || req.Path != "/test.Geo/Country" ||
+		req.Route != "/test.Geo/Country" || req.RemoteAddr != "10.0.0.1:1" {
+		t.Errorf("unexpected request view: %+v", req)
+	}
+	if ip := req.Header.Get("CF-Connecting-IP"); ip != "1.2.3.4" {
+		t.Errorf("expected metadata IP to win over the peer address, got %q", ip)
+	}
+	if ua := req.Header.Get("User-Agent"); ua != "grpc-go/1.84.0" {
+		t.Errorf("expected case-insensitive lookup, got %q", ua)
+	}
+
+	req = NewRequest(context.Background(), "/test.Geo/Country")
+	if req.Header.Get("CF-IPCountry") != "" || req.RemoteAddr != "" {
+		t.Errorf("expected empty request view without metadata, got %+v", req)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{&geolocation.StatusError{Code: http.StatusForbidden, Err: geolocation.ErrLocationUnavailable}, codes.PermissionDenied},
+		{&geolocation.StatusError{Code: http.StatusUnavailableForLegalReasons, Err: geolocation.ErrLocationUnavailable}, codes.PermissionDenied},
+		{&geolocation.StatusError{Code: http.StatusTooManyRequests, Err: geolocation.ErrLocationUnavailable}, codes.ResourceExhausted},
+		{status.Error(codes.FailedPrecondition, "custom"), codes.FailedPrecondition},
+		{errors.New("plain"), codes.Internal},
+	}
+	for _, c := range cases {
+		if got := status.Code(statusError(c.err)); got != c.code {
+			t.Errorf("statusError(%v) = %v, want %v", c.err, got, c.code)
+		}
+	}
+}
+
+func TestInterceptors_GeoFence(t *testing.T) {
+	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+		Rules:    []geolocation.FenceRule{{Mode: geolocation.FenceDeny, Countries: []string{"BG"}}},
+		Response: geolocation.FenceResponse{Redirect: "/elsewhere"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := newClient(t, geolocation.WithGeoFence(fence))
+
+	if _, err := callCountry(cloudflareContext(), conn); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a fenced country, got %v", err)
+	}
+	if _, err := callWatch(cloudflareContext(), conn); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied for a fenced stream, got %v", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "cf-ipcountry", "DE")
+	if got, err := callCountry(ctx, conn); err != nil || !strings.HasPrefix(got, "DE|") {
+		t.Errorf("expected DE to be allowed, got %q (err %v)", got, err)
+	}
+}
@@ -7,20 +7,76 @@ import (
 	"go.rumenx.com/geolocation"
 )
 
-// contextKey is used for storing location in context.
-type contextKey struct{}
-
 // HTTPMiddleware adds geolocation information to the request context.
+// The full GeoInfo is available lazily through GeoInfoFromContext.
 func HTTPMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		loc := geolocation.FromRequest(r)
-		ctx := context.WithValue(r.Context(), contextKey{}, loc)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	return Middleware()(next)
+}
+
+// GeoInfoMiddleware adds the Location and a lazily computed GeoInfo to the request context.
+// opts selects which parts of GeoInfo are computed.
+func GeoInfoMiddleware(opts geolocation.GeoInfoOptions) func(http.Handler) http.Handler {
+	return Middleware(geolocation.WithGeoInfo(opts))
+}
+
+// Middleware returns a middleware configured with opts.
+// Without options it behaves like HTTPMiddleware.
+// The Location and RequestInfo are stored with geolocation.NewContextKey, so the accessors
+// in this package and in the geolocation package can both read them.
+//
+// Example:
+//
+//	mw := httpadapter.Middleware(
+//		geolocation.WithSkipPaths("/health"),
+//		geolocation.WithFallbackCountry("US"),
+//	)
+func Middleware(opts ...geolocation.Option) func(http.Handler) http.Handler {
+	return geolocation.Middleware(opts...)
+}
+
+// GeoFenceMiddleware returns a middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+func GeoFenceMiddleware(fence *geolocation.GeoFence, opts ...geolocation.Option) func(http.Handler) http.Handler {
+	return Middleware(append([]geolocation.Option{geolocation.WithGeoFence(fence)}, opts...)...)
+}
+
+// ServeMux wraps mux with a middleware configured with opts. Unlike Middleware(opts...)(mux),
+// requests are matched against mux first, so WithSkipRoutes and WithRoutePolicy can use
+// ServeMux patterns such as "GET /users/{id}".
+//
+// Example:
+//
+//	mux.HandleFunc("GET /users/{id}", getUser)
+//	mux.HandleFunc("GET /health", health)
+//	http.ListenAndServe(":8080", httpadapter.ServeMux(mux, geolocation.WithSkipRoutes("GET /health")))
+func ServeMux(mux *http.ServeMux, opts ...geolocation.Option) http.Handler {
+	return geolocation.RouteMiddleware(func(r *http.Request) string {
+		_, pattern := mux.Handler(r)
+		return pattern
+	}, opts...)(mux)
 }
 
 // FromContext retrieves the geolocation info from the context.
 func FromContext(ctx context.Context) *geolocation.Location {
-	loc, _ := ctx.Value(contextKey{}).(*geolocation.Location)
-	return loc
+	return geolocation.FromContext(ctx)
+}
+
+// FromContextKey retrieves the Location stored under a key set with geolocation.WithContextKey.
+func FromContextKey(ctx context.Context, key string) *geolocation.Location {
+	return geolocation.FromContextKey(ctx, key)
+}
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from the context.
+func RequestInfoFromContext(ctx context.Context) *geolocation.RequestInfo {
+	return geolocation.RequestInfoFromContext(ctx)
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from the context, computing it on first access.
+func GeoInfoFromContext(ctx context.Context) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContext(ctx)
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with geolocation.WithContextKey.
+func GeoInfoFromContextKey(ctx context.Context, key string) *geolocation.GeoInfo {
+	return geolocation.GeoInfoFromContextKey(ctx, key)
 }
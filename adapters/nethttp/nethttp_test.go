@@ -36,18 +36,175 @@ func TestHTTPMiddleware_WithHeaders(t *testing.T) {
 }
 
 func TestFromContext(t *testing.T) {
-	ctx := context.Background()
-	loc := &geolocation.Location{IP: "1.2.3.4"}
-	ctx = context.WithValue(ctx, contextKey{}, loc)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-Connecting-IP", "1.2.3.4")
+	ctx := geolocation.NewContext(context.Background(), geolocation.NewRequestInfo(r, geolocation.DefaultGeoInfoOptions()))
 	got := FromContext(ctx)
 	if got == nil || got.IP != "1.2.3.4" {
 		t.Error("FromContext did not return correct location")
 	}
 }
 
+func TestMiddleware_SharedContext(t *testing.T) {
+	h := Middleware(geolocation.WithGeoInfo(geolocation.DefaultGeoInfoOptions()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if loc := geolocation.FromContext(ctx); loc == nil || loc != FromContext(ctx) || loc.Country != "DE" {
+			t.Errorf("expected the location to be shared with the geolocation package, got %+v", loc)
+		}
+		if geolocation.RequestInfoFromContext(ctx) == nil || geolocation.ComplianceFromContext(ctx) == nil {
+			t.Error("expected the geolocation package accessors to find the RequestInfo")
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "DE")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
 func TestFromContext_Nil(t *testing.T) {
 	ctx := context.Background()
 	if loc := FromContext(ctx); loc != nil {
 		t.Error("expected nil location from empty context")
 	}
 }
+
+func TestHTTPMiddleware_GeoInfo(t *testing.T) {
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := GeoInfoFromContext(r.Context())
+		if info == nil || info.CountryCode != "DE" || info.PreferredLanguage != "de" {
+			t.Errorf("unexpected GeoInfo: %+v", info)
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "DE")
+	req.Header.Set("Accept-Language", "de,en;q=0.5")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoInfoMiddleware_Options(t *testing.T) {
+	h := GeoInfoMiddleware(geolocation.GeoInfoOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loc := FromContext(r.Context()); loc == nil || loc.Country != "DE" {
+			t.Errorf("expected location, got %+v", loc)
+		}
+		info := GeoInfoFromContext(r.Context())
+		if info == nil || info.CountryCode != "DE" || info.PreferredLanguage != "" {
+			t.Errorf("expected language part to be disabled, got %+v", info)
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "DE")
+	req.Header.Set("Accept-Language", "de")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoInfoFromContext_Nil(t *testing.T) {
+	if GeoInfoFromContext(context.Background()) != nil || RequestInfoFromContext(context.Background()) != nil {
+		t.Error("expected nil from empty context")
+	}
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	h := Middleware(
+		geolocation.WithSkipPaths("/health"),
+		geolocation.WithContextKey("geo"),
+		geolocation.WithFallbackCountry("US"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			if FromContextKey(r.Context(), "geo") != nil {
+				t.Error("expected skipped path to have no location")
+			}
+			return
+		}
+		if loc := FromContextKey(r.Context(), "geo"); loc == nil || loc.Country != "US" {
+			t.Errorf("expected fallback country under custom key, got %+v", loc)
+		}
+		if info := GeoInfoFromContextKey(r.Context(), "geo"); info == nil || info.CountryCode != "US" {
+			t.Errorf("expected GeoInfo under custom key, got %+v", info)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+}
+
+func TestMiddleware_ErrorHandler(t *testing.T) {
+	h := Middleware(geolocation.WithErrorHandler(func(err error) error {
+		return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", rec.Code)
+	}
+}
+
+func TestContextKeyHelpers_Default(t *testing.T) {
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if FromContextKey(r.Context(), geolocation.DefaultContextKey) == nil || GeoInfoFromContextKey(r.Context(), geolocation.DefaultContextKey) == nil {
+			t.Error("expected default key lookups to find the location")
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestServeMux(t *testing.T) {
+	var reached []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		reached = append(reached, r.URL.Path)
+		if loc := FromContext(r.Context()); loc == nil || loc.Country != "DE" {
+			t.Errorf("expected location DE, got %+v", loc)
+		}
+	})
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		reached = append(reached, r.URL.Path)
+		if FromContext(r.Context()) != nil {
+			t.Error("expected skipped route to have no location")
+		}
+	})
+	h := ServeMux(mux,
+		geolocation.WithSkipRoutes("GET /health"),
+		geolocation.WithRoutePolicy("GET /users/{id}", geolocation.WithFallbackCountry("DE")),
+		geolocation.WithErrorHandler(func(err error) error {
+			return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+		}),
+	)
+
+	for _, path := range []string{"/users/1", "/health"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", path, rec.Code)
+		}
+	}
+	if len(reached) != 2 {
+		t.Errorf("expected both routes to be reached, got %v", reached)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected unmatched route to use the base options, got %d", rec.Code)
+	}
+}
+
+func TestGeoFenceMiddleware(t *testing.T) {
+	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+		Rules: []geolocation.FenceRule{{Mode: geolocation.FenceDeny, Regions: []string{"US-CA"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := GeoFenceMiddleware(fence)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for region, status := range map[string]int{"NY": http.StatusOK, "CA": http.StatusForbidden} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("CF-IPCountry", "US")
+		req.Header.Set("CF-Region-Code", region)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("US-%s: expected status %d, got %d", region, status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Area is a named polygonal area, such as a delivery zone, loaded from a GeoJSON feature.
+type Area struct {
+	ID         string         // Feature id, or its "id" property
+	Name       string         // The "name" property, if any
+	Properties map[string]any // All feature properties
+
+	polygons []polygon
+	box      box
+}
+
+// AreaIndex answers which areas contain a point. It is immutable and safe for concurrent use.
+// Each area and polygon carries a bounding box, so most areas are rejected without
+// running the point-in-polygon test.
+type AreaIndex struct {
+	areas []*Area
+}
+
+// polygon is an outer ring followed by optional holes, with the bounding box of the outer ring.
+type polygon struct {
+	rings [][]point
+	box   box
+}
+
+type point struct {
+	lat, lon float64
+}
+
+// box is a latitude/longitude bounding box.
+type box struct {
+	minLat, minLon, maxLat, maxLon float64
+}
+
+// geoJSON covers the parts of a GeoJSON FeatureCollection or Feature that are used.
+type geoJSON struct {
+	Type       string         `json:"type"`
+	Features   []geoJSON      `json:"features"`
+	ID         any            `json:"id"`
+	Properties map[string]any `json:"properties"`
+	Geometry   *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+// LoadAreas loads areas from a GeoJSON file. See ParseAreas.
+func LoadAreas(path string) (*AreaIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseAreas(data)
+}
+
+// ParseAreas parses a GeoJSON FeatureCollection (or a single Feature) whose features have
+// Polygon or MultiPolygon geometries. Features without an id or "id" property are
+// identified by their index. Polygons must not cross the antimeridian (RFC 7946 section 3.1.9).
+//
+// Example:
+//
+//	areas, err := geolocation.LoadAreas("delivery-zones.geojson")
+//	ids := areas.Locate(geolocation.FromRequest(r)) // e.g. ["sofia-center"]
+func ParseAreas(data []byte) (*AreaIndex, error) {
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("geolocation: invalid GeoJSON: %w", err)
+	}
+	features := doc.Features
+	switch doc.Type {
+	case "FeatureCollection":
+	case "Feature":
+		features = []geoJSON{doc}
+	default:
+		return nil, fmt.Errorf("geolocation: unsupported GeoJSON type %q", doc.Type)
+	}
+
+	ix := &AreaIndex{}
+	for i, f := range features {
+		area, err := newArea(f, i)
+		if err != nil {
+			return nil, fmt.Errorf("geolocation: GeoJSON feature %d: %w", i, err)
+		}
+		ix.areas = append(ix.areas, area)
+	}
+	return ix, nil
+}
+
+// newArea converts a feature into an Area.
+func newArea(f geoJSON, index int) (*Area, error) {
+	if f.Geometry == nil {
+		return nil, fmt.Errorf("missing geometry")
+	}
+	a := &Area{Properties: f.Properties, ID: featureID(f, index)}
+	a.Name, _ = f.Properties["name"].(string)
+
+	var polys [][][][]float64
+	switch f.Geometry.Type {
+	case "Polygon":
+		var p [][][]float64
+		if err := json.Unmarshal(f.Geometry.Coordinates, &p); err != nil {
+			return nil, err
+		}
+		polys = [][][][]float64{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(f.Geometry.Coordinates, &polys); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry %q, expected Polygon or MultiPolygon", f.Geometry.Type)
+	}
+
+	a.box = emptyBox()
+	for _, rings := range polys {
+		if len(rings) == 0 {
+			continue
+		}
+		p := polygon{box: emptyBox()}
+		for r, ring := range rings {
+			if len(ring) < 4 {
+				return nil, fmt.Errorf("ring with %d positions, expected at least 4", len(ring))
+			}
+			pts := make([]point, 0, len(ring))
+			for _, pos := range ring {
+				if len(pos) < 2 {
+					return nil, fmt.Errorf("position with %d coordinates", len(pos))
+				}
+				pt := point{lat: pos[1], lon: pos[0]}
+				pts = append(pts, pt)
+				if r == 0 {
+					p.box.extend(pt)
+				}
+			}
+			p.rings = append(p.rings, pts)
+		}
+		a.polygons = append(a.polygons, p)
+		a.box.union(p.box)
+	}
+	if len(a.polygons) == 0 {
+		return nil, fmt.Errorf("empty geometry")
+	}
+	return a, nil
+}
+
+// featureID returns the feature id, its "id" property, or its index.
+func featureID(f geoJSON, index int) string {
+	id := f.ID
+	if id == nil {
+		id = f.Properties["id"]
+	}
+	switch v := id.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.Itoa(index)
+}
+
+// Len returns the number of areas.
+func (ix *AreaIndex) Len() int {
+	return len(ix.areas)
+}
+
+// Find returns the areas containing the point, in file order.
+func (ix *AreaIndex) Find(lat, lon float64) []*Area {
+	var found []*Area
+	pt := point{lat: lat, lon: lon}
+	for _, a := range ix.areas {
+		if a.contains(pt) {
+			found = append(found, a)
+		}
+	}
+	return found
+}
+
+// Contains returns the IDs of the areas containing the point, in file order.
+func (ix *AreaIndex) Contains(lat, lon float64) []string {
+	var ids []string
+	for _, a := range ix.Find(lat, lon) {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
+
+// Locate returns the IDs of the areas containing loc, or nil if loc has no coordinates.
+func (ix *AreaIndex) Locate(loc *Location) []string {
+	if loc == nil || !loc.HasCoordinates() {
+		return nil
+	}
+	return ix.Contains(loc.Latitude, loc.Longitude)
+}
+
+// Contains reports whether the area contains the point.
+func (a *Area) Contains(lat, lon float64) bool {
+	return a.contains(point{lat: lat, lon: lon})
+}
+
+func (a *Area) contains(pt point) bool {
+	if !a.box.contains(pt) {
+		return false
+	}
+	for _, p := range a.polygons {
+		if p.contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether pt is inside the outer ring and outside every hole.
+func (p *polygon) contains(pt point) bool {
+	if !p.box.contains(pt) || !inRing(p.rings[0], pt) {
+		return false
+	}
+	for _, hole := range p.rings[1:] {
+		if inRing(hole, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing is the even-odd ray casting test, treating coordinates as planar.
+func inRing(ring []point, pt point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.lat > pt.lat) != (b.lat > pt.lat) &&
+			pt.lon < (b.lon-a.lon)*(pt.lat-a.lat)/(b.lat-a.lat)+a.lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func emptyBox() box {
+	return box{minLat: 90, minLon: 180, maxLat: -90, maxLon: -180}
+}
+
+func (b *box) extend(pt point) {
+	b.minLat, b.maxLat = min(b.minLat, pt.lat), max(b.maxLat, pt.lat)
+	b.minLon, b.maxLon = min(b.minLon, pt.lon), max(b.maxLon, pt.lon)
+}
+
+func (b *box) union(o box) {
+	b.minLat, b.maxLat = min(b.minLat, o.minLat), max(b.maxLat, o.maxLat)
+	b.minLon, b.maxLon = min(b.minLon, o.minLon), max(b.maxLon, o.maxLon)
+}
+
+func (b box) contains(pt point) bool {
+	return pt.lat >= b.minLat && pt.lat <= b.maxLat && pt.lon >= b.minLon && pt.lon <= b.maxLon
+}
@@ -0,0 +1,193 @@
+package geolocation
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAreas = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "id": "square",
+      "properties": {"name": "Square with a hole"},
+      "geometry": {"type": "Polygon", "coordinates": [
+        [[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+        [[4, 4], [6, 4], [6, 6], [4, 6], [4, 4]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "id": 7,
+      "properties": {"name": "Islands"},
+      "geometry": {"type": "MultiPolygon", "coordinates": [
+        [[[20, 20], [22, 20], [22, 22], [20, 22], [20, 20]]],
+        [[[30, 30], [32, 30], [31, 33], [30, 30]]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"id": "overlap"},
+      "geometry": {"type": "Polygon", "coordinates": [[[8, 8, 100], [12, 8, 100], [12, 12, 100], [8, 12, 100], [8, 8, 100]]]}
+    },
+    {
+      "type": "Feature",
+      "properties": {},
+      "geometry": {"type": "Polygon", "coordinates": [[[-10, -10], [-5, -10], [-5, -5], [-10, -5], [-10, -10]]]}
+    }
+  ]
+}`
+
+func TestParseAreas(t *testing.T) {
+	ix, err := ParseAreas([]byte(testAreas))
+	if err != nil {
+		t.Fatalf("ParseAreas failed: %v", err)
+	}
+	if ix.Len() != 4 {
+		t.Fatalf("expected 4 areas, got %d", ix.Len())
+	}
+
+	cases := []struct {
+		lat, lon float64
+		want     []string
+	}{
+		{1, 1, []string{"square"}},
+		{5, 5, nil}, // in the hole
+		{9, 9, []string{"square", "overlap"}},
+		{11, 11, []string{"overlap"}},
+		{21, 21, []string{"7"}},
+		{31, 31, []string{"7"}},
+		{32.5, 30.2, nil}, // inside the bounding box, outside the triangle
+		{-7, -7, []string{"3"}},
+		{50, 50, nil},
+	}
+	for _, c := range cases {
+		if got := ix.Contains(c.lat, c.lon); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Contains(%v, %v) = %v, want %v", c.lat, c.lon, got, c.want)
+		}
+	}
+
+	found := ix.Find(21, 21)
+	if len(found) != 1 || found[0].Name != "Islands" || !found[0].Contains(31, 31) {
+		t.Errorf("unexpected areas: %+v", found)
+	}
+}
+
+func TestParseAreas_Errors(t *testing.T) {
+	cases := map[string]string{
+		"not json":       `{`,
+		"bad type":       `{"type": "Polygon", "coordinates": []}`,
+		"point":          `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}}`,
+		"no geometry":    `{"type": "Feature", "properties": {}}`,
+		"short ring":     `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 1], [0, 0]]]}}`,
+		"bad position":   `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0], [1, 1], [1, 0], [0]]]}}`,
+		"empty geometry": `{"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": []}}`,
+	}
+	for name, data := range cases {
+		if _, err := ParseAreas([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadAreas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "areas.geojson")
+	os.WriteFile(path, []byte(testAreas), 0o600)
+	ix, err := LoadAreas(path)
+	if err != nil || ix.Len() != 4 {
+		t.Fatalf("LoadAreas failed: %v", err)
+	}
+	if _, err := LoadAreas(filepath.Join(t.TempDir(), "missing.geojson")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestAreaIndex_Locate(t *testing.T) {
+	ix, _ := ParseAreas([]byte(testAreas))
+	if got := ix.Locate(&Location{Country: "BG"}); got != nil {
+		t.Errorf("expected nil without coordinates, got %v", got)
+	}
+	if got := ix.Locate(&Location{Latitude: 1, Longitude: 2}); !reflect.DeepEqual(got, []string{"square"}) {
+		t.Errorf("unexpected areas: %v", got)
+	}
+}
+
+func TestFromHeaders_Coordinates(t *testing.T) {
+	cases := []struct {
+		lat, lon   string
+		wantLat    float64
+		wantLon    float64
+		wantCoords bool
+	}{
+		{"42.6977", "23.3219", 42.6977, 23.3219, true},
+		{"-90", "180", -90, 180, true},
+		{"91", "0", 0, 0, false},
+		{"10", "-181", 0, 0, false},
+		{"NaN", "10", 0, 0, false},
+		{"10", "", 0, 0, false},
+	}
+	for _, c := range cases {
+		loc := FromHeaders(mapHeaders{"cf-iplatitude": c.lat, "cf-iplongitude": c.lon})
+		if loc.Latitude != c.wantLat || loc.Longitude != c.wantLon || loc.HasCoordinates() != c.wantCoords {
+			t.Errorf("FromHeaders(%q, %q) = %v, %v", c.lat, c.lon, loc.Latitude, loc.Longitude)
+		}
+	}
+}
+
+func TestAreasMiddleware(t *testing.T) {
+	ix, _ := ParseAreas([]byte(testAreas))
+	var got []string
+	h := AreasMiddleware(ix)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = AreasFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPLatitude", "9")
+	r.Header.Set("CF-IPLongitude", "9")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if !reflect.DeepEqual(got, []string{"square", "overlap"}) {
+		t.Errorf("unexpected areas in context: %v", got)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got != nil {
+		t.Errorf("expected no areas without coordinates, got %v", got)
+	}
+
+	HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if AreasFromContext(r.Context()) != nil {
+			t.Error("expected no areas without WithAreas")
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+// benchmarkAreas builds a grid of n×n one-degree square areas.
+func benchmarkAreas(n int) []byte {
+	var features []string
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			features = append(features, fmt.Sprintf(
+				`{"type":"Feature","id":"%d-%d","geometry":{"type":"Polygon","coordinates":[[[%d,%d],[%d,%d],[%d,%d],[%d,%d],[%d,%d]]]}}`,
+				i, j, i, j, i+1, j, i+1, j+1, i, j+1, i, j))
+		}
+	}
+	return []byte(`{"type":"FeatureCollection","features":[` + strings.Join(features, ",") + `]}`)
+}
+
+func BenchmarkAreaIndex_Contains(b *testing.B) {
+	ix, err := ParseAreas(benchmarkAreas(30))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.Contains(15.5, 15.5)
+	}
+}
@@ -0,0 +1,14 @@
+// go-geolocation screen beacon: stores screen metrics in the geo_screen cookie
+// so that geolocation.GetResolution can read them on subsequent requests.
+(function () {
+  var s = window.screen || {};
+  var d = document.documentElement || {};
+  var v = [
+    "w=" + (s.width || 0),
+    "h=" + (s.height || 0),
+    "dpr=" + (window.devicePixelRatio || 1),
+    "vw=" + (window.innerWidth || d.clientWidth || 0),
+    "vh=" + (window.innerHeight || d.clientHeight || 0)
+  ].join("&");
+  document.cookie = "geo_screen=" + v + "; path=/; max-age=2592000; SameSite=Lax";
+})();
@@ -0,0 +1,154 @@
+package geolocation
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultUACacheSize is the number of entries used when EnableUACache is called with a non-positive size.
+const DefaultUACacheSize = 1024
+
+// MaxUACacheKeyLength is the longest User-Agent that will be cached.
+// Longer values are parsed on every call so that oversized headers cannot fill the cache.
+const MaxUACacheKeyLength = 512
+
+// UACache is a concurrency-safe, bounded LRU cache of parsed User-Agent results.
+type UACache struct {
+	mu     sync.Mutex
+	size   int
+	ll     *list.List
+	items  map[string]*list.Element
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// UACacheStats holds cache counters.
+type UACacheStats struct {
+	Hits   uint64 // Lookups served from the cache
+	Misses uint64 // Lookups that required parsing
+	Len    int    // Current number of entries
+	Size   int    // Maximum number of entries
+}
+
+type uaCacheEntry struct {
+	key  string
+	info ClientInfo
+}
+
+// uaCache is the package-wide cache used by ParseClientInfo when enabled.
+var uaCache atomic.Pointer[UACache]
+
+// NewUACache creates an LRU cache holding at most size entries.
+// A non-positive size uses DefaultUACacheSize.
+func NewUACache(size int) *UACache {
+	if size <= 0 {
+		size = DefaultUACacheSize
+	}
+	return &UACache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// EnableUACache installs a new package-wide User-Agent cache of the given size and returns it.
+// ParseClientInfo and GetGeoInfo use it until DisableUACache is called.
+//
+// Example:
+//
+//	cache := geolocation.EnableUACache(4096)
+//	stats := cache.Stats()
+//	fmt.Println(stats.Hits, stats.Misses)
+func EnableUACache(size int) *UACache {
+	c := NewUACache(size)
+	uaCache.Store(c)
+	return c
+}
+
+// DisableUACache removes the package-wide User-Agent cache.
+func DisableUACache() {
+	uaCache.Store(nil)
+}
+
+// clientInfoFor parses a User-Agent, going through the package-wide cache when enabled.
+func clientInfoFor(userAgent string) *ClientInfo {
+	if c := uaCache.Load(); c != nil {
+		return c.Parse(userAgent)
+	}
+	return parseUserAgent(userAgent)
+}
+
+// Parse returns the ClientInfo for a User-Agent, parsing and caching it on a miss.
+// The returned value is a copy and may be modified by the caller.
+func (c *UACache) Parse(userAgent string) *ClientInfo {
+	if len(userAgent) > MaxUACacheKeyLength {
+		c.misses.Add(1)
+		return parseUserAgent(userAgent)
+	}
+	if info, ok := c.get(userAgent); ok {
+		c.hits.Add(1)
+		return info
+	}
+	c.misses.Add(1)
+	info := parseUserAgent(userAgent)
+	c.add(userAgent, info)
+	return info
+}
+
+// get returns a copy of the cached entry and marks it as recently used.
+func (c *UACache) get(key string) (*ClientInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	info := el.Value.(*uaCacheEntry).info
+	return &info, true
+}
+
+// add stores a copy of info, evicting the least recently used entry when full.
+func (c *UACache) add(key string, info *ClientInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*uaCacheEntry).info = *info
+		return
+	}
+	c.items[key] = c.ll.PushFront(&uaCacheEntry{key: key, info: *info})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*uaCacheEntry).key)
+	}
+}
+
+// Len returns the number of cached entries.
+func (c *UACache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Stats returns the current hit/miss counters and occupancy.
+func (c *UACache) Stats() UACacheStats {
+	return UACacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Len:    c.Len(),
+		Size:   c.size,
+	}
+}
+
+// Purge removes all entries and resets the counters.
+func (c *UACache) Purge() {
+	c.mu.Lock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element, c.size)
+	c.mu.Unlock()
+	c.hits.Store(0)
+	c.misses.Store(0)
+}
@@ -0,0 +1,161 @@
+package geolocation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const benchUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36"
+
+func TestUACache_HitMiss(t *testing.T) {
+	c := NewUACache(2)
+	first := c.Parse(benchUserAgent)
+	second := c.Parse(benchUserAgent)
+	if first.BrowserName != "Chrome" || *first != *second {
+		t.Errorf("unexpected cached result: %+v vs %+v", first, second)
+	}
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Len != 1 || stats.Size != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestUACache_ReturnsCopy(t *testing.T) {
+	c := NewUACache(2)
+	info := c.Parse(benchUserAgent)
+	info.BrowserName = "Modified"
+	if got := c.Parse(benchUserAgent); got.BrowserName != "Chrome" {
+		t.Errorf("cached entry was modified through returned value: %+v", got)
+	}
+}
+
+func TestUACache_Eviction(t *testing.T) {
+	c := NewUACache(2)
+	c.Parse("a")
+	c.Parse("b")
+	c.Parse("a") // a becomes most recently used
+	c.Parse("c") // evicts b
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+	before := c.Stats().Misses
+	c.Parse("a")
+	if c.Stats().Misses != before {
+		t.Error("expected 'a' to still be cached")
+	}
+	c.Parse("b")
+	if c.Stats().Misses != before+1 {
+		t.Error("expected 'b' to have been evicted")
+	}
+}
+
+func TestUACache_KeyLengthCap(t *testing.T) {
+	c := NewUACache(2)
+	long := strings.Repeat("x", MaxUACacheKeyLength+1)
+	c.Parse(long)
+	c.Parse(long)
+	if stats := c.Stats(); stats.Len != 0 || stats.Misses != 2 || stats.Hits != 0 {
+		t.Errorf("expected oversized key not to be cached, got %+v", stats)
+	}
+}
+
+func TestUACache_DefaultSizeAndPurge(t *testing.T) {
+	c := NewUACache(0)
+	if c.Stats().Size != DefaultUACacheSize {
+		t.Errorf("expected default size %d, got %d", DefaultUACacheSize, c.Stats().Size)
+	}
+	c.Parse(benchUserAgent)
+	c.Parse(benchUserAgent)
+	c.Purge()
+	if stats := c.Stats(); stats.Len != 0 || stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("expected empty cache after purge, got %+v", stats)
+	}
+}
+
+func TestUACache_Concurrent(t *testing.T) {
+	c := NewUACache(8)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				ua := fmt.Sprintf("%s %d", benchUserAgent, (i+j)%12)
+				if info := c.Parse(ua); info.BrowserName == "" {
+					t.Errorf("unexpected empty browser for %q", ua)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	stats := c.Stats()
+	if stats.Len > 8 || stats.Hits+stats.Misses != 16*200 {
+		t.Errorf("unexpected stats after concurrent use: %+v", stats)
+	}
+}
+
+func TestEnableUACache(t *testing.T) {
+	cache := EnableUACache(16)
+	defer DisableUACache()
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", benchUserAgent)
+	ParseClientInfo(r)
+	GetGeoInfo(r)
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("expected ParseClientInfo and GetGeoInfo to use the cache, got %+v", stats)
+	}
+
+	DisableUACache()
+	ParseClientInfo(r)
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 2 {
+		t.Errorf("expected disabled cache not to be used, got %+v", stats)
+	}
+}
+
+func BenchmarkParseClientInfo(b *testing.B) {
+	DisableUACache()
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", benchUserAgent)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseClientInfo(r)
+	}
+}
+
+func BenchmarkParseClientInfo_Cached(b *testing.B) {
+	EnableUACache(DefaultUACacheSize)
+	defer DisableUACache()
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", benchUserAgent)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseClientInfo(r)
+	}
+}
+
+func BenchmarkGetGeoInfo(b *testing.B) {
+	DisableUACache()
+	r := SimulateRequest("DE", &SimulationOptions{UserAgent: benchUserAgent})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GetGeoInfo(r)
+	}
+}
+
+func BenchmarkGetGeoInfo_Cached(b *testing.B) {
+	EnableUACache(DefaultUACacheSize)
+	defer DisableUACache()
+	r := SimulateRequest("DE", &SimulationOptions{UserAgent: benchUserAgent})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GetGeoInfo(r)
+	}
+}
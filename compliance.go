@@ -0,0 +1,170 @@
+package geolocation
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed assets/jurisdictions.json
+var jurisdictionsJSON []byte
+
+// Jurisdiction is a privacy regime that applies to visitors from the listed countries or regions.
+type Jurisdiction struct {
+	ID              string   `json:"id"`               // e.g. GDPR, CCPA
+	Name            string   `json:"name"`             // Human-readable name of the law
+	Countries       []string `json:"countries"`        // ISO 3166-1 alpha-2 codes
+	Regions         []string `json:"regions"`          // ISO 3166-2 codes, e.g. US-CA
+	ConsentRequired bool     `json:"consent_required"` // Opt-in: tracking needs prior consent
+}
+
+type jurisdictionList struct {
+	Version       string         `json:"version"`
+	Jurisdictions []Jurisdiction `json:"jurisdictions"`
+}
+
+// jurisdictions parses the embedded list on first use.
+var jurisdictions = sync.OnceValue(func() jurisdictionList {
+	var list jurisdictionList
+	if err := json.Unmarshal(jurisdictionsJSON, &list); err != nil {
+		panic("geolocation: invalid embedded jurisdiction list: " + err.Error())
+	}
+	return list
+})
+
+// JurisdictionsVersion returns the date of the embedded jurisdiction list, e.g. 2026-10-01.
+// Record it alongside consent decisions so they can be traced back to the rules in force.
+func JurisdictionsVersion() string {
+	return jurisdictions().Version
+}
+
+// Jurisdictions returns a copy of the embedded jurisdiction list.
+func Jurisdictions() []Jurisdiction {
+	list := jurisdictions().Jurisdictions
+	out := make([]Jurisdiction, len(list))
+	for i, j := range list {
+		j.Countries = slices.Clone(j.Countries)
+		j.Regions = slices.Clone(j.Regions)
+		out[i] = j
+	}
+	return out
+}
+
+// JurisdictionsFor returns the IDs of the jurisdictions covering a country and, optionally,
+// a region within it (without the country prefix, as in Location.Region). Codes are case-insensitive.
+func JurisdictionsFor(country, region string) []string {
+	if country == "" {
+		return nil
+	}
+	country, region = strings.ToUpper(country), strings.ToUpper(region)
+	var ids []string
+	for _, j := range jurisdictions().Jurisdictions {
+		if slices.Contains(j.Countries, country) || region != "" && slices.Contains(j.Regions, country+"-"+region) {
+			ids = append(ids, j.ID)
+		}
+	}
+	return ids
+}
+
+// Compliance summarizes the privacy rules and signals that apply to a visitor.
+type Compliance struct {
+	GDPR            bool     `json:"gdpr"`                    // EU, EEA or UK
+	CCPA            bool     `json:"ccpa"`                    // California
+	Jurisdictions   []string `json:"jurisdictions,omitempty"` // IDs of all matching jurisdictions
+	ConsentRequired bool     `json:"consent_required"`        // Opt-in applies, or the location is unknown
+	GPC             bool     `json:"gpc"`                     // Sec-GPC: 1 was sent
+	DNT             bool     `json:"dnt"`                     // DNT: 1 was sent
+	Version         string   `json:"version"`                 // Version of the jurisdiction list used
+}
+
+// NewCompliance evaluates the jurisdiction list for loc and reads the GPC and DNT headers.
+// A visitor without a country is treated as requiring consent, as is a visitor without a region
+// from a country where an opt-in jurisdiction covers some regions, e.g. Canada for Quebec.
+func NewCompliance(loc *Location, h Headers) *Compliance {
+	c := &Compliance{
+		Jurisdictions: JurisdictionsFor(loc.Country, loc.Region),
+		GPC:           h.Get("Sec-GPC") == "1",
+		DNT:           h.Get("DNT") == "1",
+		Version:       JurisdictionsVersion(),
+	}
+	c.ConsentRequired = loc.Country == "" || loc.Region == "" && regionalConsent(loc.Country)
+	for _, j := range jurisdictions().Jurisdictions {
+		if !slices.Contains(c.Jurisdictions, j.ID) {
+			continue
+		}
+		c.ConsentRequired = c.ConsentRequired || j.ConsentRequired
+		switch j.ID {
+		case "GDPR", "UK-GDPR":
+			c.GDPR = true
+		case "CCPA":
+			c.CCPA = true
+		}
+	}
+	return c
+}
+
+// regionalConsent reports whether an opt-in jurisdiction covers some regions of country.
+func regionalConsent(country string) bool {
+	prefix := strings.ToUpper(country) + "-"
+	for _, j := range jurisdictions().Jurisdictions {
+		if j.ConsentRequired && slices.ContainsFunc(j.Regions, func(r string) bool { return strings.HasPrefix(r, prefix) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// OptedOut reports whether the visitor sent a Global Privacy Control or Do Not Track signal.
+func (c *Compliance) OptedOut() bool {
+	return c != nil && (c.GPC || c.DNT)
+}
+
+// TrackingAllowed reports whether tracking cookies may be set without asking for consent:
+// no opt-in jurisdiction applies and the visitor has not opted out. A nil Compliance,
+// e.g. when the part is disabled in GeoInfoOptions, never allows tracking.
+func (c *Compliance) TrackingAllowed() bool {
+	return c != nil && !c.ConsentRequired && !c.OptedOut()
+}
+
+// ComplianceFromHeaders is the framework-neutral counterpart of ComplianceFromRequest.
+func ComplianceFromHeaders(h Headers) *Compliance {
+	return NewCompliance(FromHeaders(h), h)
+}
+
+// ComplianceFromRequest returns the privacy rules and signals that apply to the request.
+//
+// Example:
+//
+//	c := geolocation.ComplianceFromRequest(r)
+//	if c.ConsentRequired {
+//		showConsentBanner()
+//	}
+func ComplianceFromRequest(r *http.Request) *Compliance {
+	return ComplianceFromHeaders(r.Header)
+}
+
+// TrackingAllowed reports whether tracking cookies may be set for the request without consent.
+// See Compliance.TrackingAllowed.
+func TrackingAllowed(r *http.Request) bool {
+	return ComplianceFromRequest(r).TrackingAllowed()
+}
+
+// SetTrackingCookie sets a cookie like SetCookie, but only when TrackingAllowed(r) reports that no
+// consent is needed. It returns whether the cookie was set. Once the visitor has consented,
+// use SetCookie directly.
+//
+// Example:
+//
+//	if !geolocation.SetTrackingCookie(w, r, "_ga", id, &http.Cookie{MaxAge: 63072000}) {
+//		// ask for consent first
+//	}
+func SetTrackingCookie(w http.ResponseWriter, r *http.Request, name, value string, opts *http.Cookie) bool {
+	if !TrackingAllowed(r) {
+		return false
+	}
+	SetCookie(w, name, value, opts)
+	return true
+}
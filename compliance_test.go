@@ -0,0 +1,156 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJurisdictionsFor(t *testing.T) {
+	tests := []struct {
+		country, region string
+		want            []string
+	}{
+		{"DE", "BY", []string{"GDPR"}},
+		{"RE", "", []string{"GDPR"}},
+		{"ax", "", []string{"GDPR"}},
+		{"no", "", []string{"GDPR"}},
+		{"GB", "ENG", []string{"UK-GDPR"}},
+		{"US", "CA", []string{"CCPA"}},
+		{"US", "co", []string{"US-STATE"}},
+		{"US", "NY", nil},
+		{"US", "", nil},
+		{"CA", "QC", []string{"LAW25"}},
+		{"CA", "ON", nil},
+		{"JP", "", nil},
+		{"", "CA", nil},
+	}
+	for _, tt := range tests {
+		if got := JurisdictionsFor(tt.country, tt.region); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("JurisdictionsFor(%q, %q) = %v, want %v", tt.country, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestJurisdictions(t *testing.T) {
+	if v := JurisdictionsVersion(); len(v) != len("2006-01-02") {
+		t.Errorf("unexpected version %q", v)
+	}
+	list := Jurisdictions()
+	if len(list) == 0 {
+		t.Fatal("expected embedded jurisdictions")
+	}
+	seen := map[string]bool{}
+	for _, j := range list {
+		if j.ID == "" || seen[j.ID] || len(j.Countries)+len(j.Regions) == 0 {
+			t.Errorf("invalid jurisdiction %+v", j)
+		}
+		seen[j.ID] = true
+		for _, c := range j.Countries {
+			if _, ok := LookupCountry(c); !ok {
+				t.Errorf("jurisdiction %s lists unknown country %s", j.ID, c)
+			}
+		}
+	}
+	list[0].Countries[0] = "XX"
+	if Jurisdictions()[0].Countries[0] == "XX" {
+		t.Error("expected Jurisdictions to return a copy")
+	}
+}
+
+func TestComplianceFromRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		headers         map[string]string
+		gdpr, ccpa      bool
+		consentRequired bool
+		tracking        bool
+	}{
+		{"germany", map[string]string{"CF-IPCountry": "DE"}, true, false, true, false},
+		{"united kingdom", map[string]string{"CF-IPCountry": "GB"}, true, false, true, false},
+		{"california", map[string]string{"CF-IPCountry": "US", "CF-Region-Code": "CA"}, false, true, false, true},
+		{"california with GPC", map[string]string{"CF-IPCountry": "US", "CF-Region-Code": "CA", "Sec-GPC": "1"}, false, true, false, false},
+		{"new york", map[string]string{"CF-IPCountry": "US", "CF-Region-Code": "NY"}, false, false, false, true},
+		{"new york with DNT", map[string]string{"CF-IPCountry": "US", "CF-Region-Code": "NY", "DNT": "1"}, false, false, false, false},
+		{"DNT 0", map[string]string{"CF-IPCountry": "JP", "DNT": "0"}, false, false, false, true},
+		{"unknown location", map[string]string{}, false, false, true, false},
+		{"reunion", map[string]string{"CF-IPCountry": "RE"}, true, false, true, false},
+		{"french guiana", map[string]string{"CF-IPCountry": "GF"}, true, false, true, false},
+		{"aland", map[string]string{"CF-IPCountry": "AX"}, true, false, true, false},
+		{"quebec", map[string]string{"CF-IPCountry": "CA", "CF-Region-Code": "QC"}, false, false, true, false},
+		{"ontario", map[string]string{"CF-IPCountry": "CA", "CF-Region-Code": "ON"}, false, false, false, true},
+		{"canada without region", map[string]string{"CF-IPCountry": "CA"}, false, false, true, false},
+		{"united states without region", map[string]string{"CF-IPCountry": "US"}, false, false, false, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		c := ComplianceFromRequest(r)
+		if c.GDPR != tt.gdpr || c.CCPA != tt.ccpa || c.ConsentRequired != tt.consentRequired {
+			t.Errorf("%s: unexpected compliance %+v", tt.name, c)
+		}
+		if got := TrackingAllowed(r); got != tt.tracking {
+			t.Errorf("%s: TrackingAllowed = %v, want %v", tt.name, got, tt.tracking)
+		}
+		if c.Version != JurisdictionsVersion() {
+			t.Errorf("%s: expected version %q, got %q", tt.name, JurisdictionsVersion(), c.Version)
+		}
+	}
+
+	var nilCompliance *Compliance
+	if nilCompliance.TrackingAllowed() || nilCompliance.OptedOut() {
+		t.Error("expected nil Compliance to disallow tracking")
+	}
+}
+
+func TestSetTrackingCookie(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "FR")
+	w := httptest.NewRecorder()
+	if SetTrackingCookie(w, r, "_id", "1", nil) || w.Header().Get("Set-Cookie") != "" {
+		t.Error("expected no tracking cookie without consent in the EU")
+	}
+
+	r.Header.Set("CF-IPCountry", "JP")
+	w = httptest.NewRecorder()
+	if !SetTrackingCookie(w, r, "_id", "1", &http.Cookie{MaxAge: 60}) || !strings.Contains(w.Header().Get("Set-Cookie"), "_id=1") {
+		t.Errorf("expected tracking cookie, got %q", w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestGeoInfo_Compliance(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "IT")
+	r.Header.Set("Sec-GPC", "1")
+
+	data, _ := json.Marshal(GetGeoInfo(r))
+	if !strings.Contains(string(data), `"compliance":{"gdpr":true,"ccpa":false,"jurisdictions":["GDPR"],"consent_required":true,"gpc":true,"dnt":false`) {
+		t.Errorf("unexpected GeoInfo JSON: %s", data)
+	}
+
+	var c *Compliance
+	HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c = ComplianceFromContext(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if c == nil || !c.GDPR || !c.GPC {
+		t.Errorf("unexpected compliance in context: %+v", c)
+	}
+
+	// Fallback countries are taken into account.
+	Middleware(WithFallbackCountry("DE"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c = ComplianceFromContext(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if c == nil || !c.GDPR {
+		t.Errorf("expected GDPR for fallback country, got %+v", c)
+	}
+
+	info := NewRequestInfo(r, GeoInfoOptions{Language: true})
+	if info.Compliance() != nil || info.GeoInfo().Compliance != nil {
+		t.Error("expected no compliance when the part is disabled")
+	}
+}
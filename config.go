@@ -0,0 +1,394 @@
+package geolocation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadOption configures LoadConfig and the other config loaders.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	strict  bool
+	partial bool // The config is one layer of a merge and is validated as a whole afterwards
+}
+
+// WithStrict makes the loaders reject unknown fields, normalize country keys to uppercase
+// and return every problem reported by Config.Validate.
+//
+// Example:
+//
+//	cfg, err := geolocation.LoadConfig("config.yaml", geolocation.WithStrict())
+//	// geolocation: invalid config: country_to_language_map.CH[1] (line 4, column 12): invalid language tag "f_r"
+func WithStrict() LoadOption {
+	return func(o *loadOptions) {
+		o.strict = true
+	}
+}
+
+// ConfigFormat is the encoding of a configuration file.
+type ConfigFormat string
+
+// Supported configuration formats.
+const (
+	FormatJSON ConfigFormat = "json"
+	FormatYAML ConfigFormat = "yaml"
+	FormatTOML ConfigFormat = "toml"
+)
+
+// FormatFromPath returns the format of a configuration file from its extension (.json, .yaml/.yml or .toml).
+func FormatFromPath(path string) (ConfigFormat, error) {
+	switch {
+	case strings.HasSuffix(path, ".json"):
+		return FormatJSON, nil
+	case strings.HasSuffix(path, ".yaml"), strings.HasSuffix(path, ".yml"):
+		return FormatYAML, nil
+	case strings.HasSuffix(path, ".toml"):
+		return FormatTOML, nil
+	}
+	return "", errors.New("unsupported config file format")
+}
+
+// LoadConfig loads configuration from a JSON, YAML or TOML file.
+// The format is determined by the file extension (.json, .yaml/.yml or .toml).
+func LoadConfig(path string, opts ...LoadOption) (*Config, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(data, format, newLoadOptions(opts))
+}
+
+// LoadConfigReader loads configuration in the given format from r.
+//
+// Example:
+//
+//	cfg, err := geolocation.LoadConfigReader(resp.Body, geolocation.FormatJSON)
+func LoadConfigReader(r io.Reader, format ConfigFormat, opts ...LoadOption) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(data, format, newLoadOptions(opts))
+}
+
+// LoadConfigFS loads configuration from a file in fsys, such as an embed.FS.
+// The format is determined by the file extension, as for LoadConfig.
+//
+// Example:
+//
+//	//go:embed config.yaml
+//	var configFS embed.FS
+//
+//	cfg, err := geolocation.LoadConfigFS(configFS, "config.yaml")
+func LoadConfigFS(fsys fs.FS, name string, opts ...LoadOption) (*Config, error) {
+	format, err := FormatFromPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(data, format, newLoadOptions(opts))
+}
+
+func newLoadOptions(opts []LoadOption) loadOptions {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// decodeConfig parses data in the given format and applies o.
+func decodeConfig(data []byte, format ConfigFormat, o loadOptions) (*Config, error) {
+	cfg := &Config{}
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if o.strict {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(cfg); err != nil {
+			return nil, err
+		}
+		if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+			return nil, errors.New("geolocation: invalid config: data after top-level value")
+		}
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(o.strict)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err == nil {
+			cfg.positions = map[string]position{}
+			recordPositions(&root, "", cfg.positions)
+		}
+	case FormatTOML:
+		dec := toml.NewDecoder(bytes.NewReader(data))
+		if o.strict {
+			dec.DisallowUnknownFields()
+		}
+		if err := dec.Decode(cfg); err != nil {
+			return nil, tomlError(err)
+		}
+	default:
+		return nil, fmt.Errorf("geolocation: unsupported config format %q", format)
+	}
+	if o.strict {
+		if err := cfg.normalize(); err != nil {
+			return nil, err
+		}
+		if o.partial {
+			return cfg, nil
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// tomlError adds the positions and keys that go-toml keeps out of its error messages.
+func tomlError(err error) error {
+	var missing *toml.StrictMissingError
+	if errors.As(err, &missing) {
+		msgs := make([]string, len(missing.Errors))
+		for i, e := range missing.Errors {
+			line, column := e.Position()
+			msgs[i] = fmt.Sprintf("%s (line %d, column %d): unknown field", strings.Join(e.Key(), "."), line, column)
+		}
+		return errors.New("geolocation: invalid config: " + strings.Join(msgs, "; "))
+	}
+	var decode *toml.DecodeError
+	if errors.As(err, &decode) {
+		line, column := decode.Position()
+		return fmt.Errorf("geolocation: invalid config: line %d, column %d: %w", line, column, err)
+	}
+	return err
+}
+
+// position is a line and column in a config file, both starting at 1.
+type position struct {
+	line, column int
+}
+
+// recordPositions stores the position of every mapping key and sequence item below n by field path,
+// e.g. country_to_language_map.CH[1].
+func recordPositions(n *yaml.Node, path string, out map[string]position) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			recordPositions(c, path, out)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			p := key.Value
+			if path != "" {
+				p = path + "." + key.Value
+			}
+			out[p] = position{key.Line, key.Column}
+			recordPositions(value, p, out)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			out[p] = position{c.Line, c.Column}
+			recordPositions(c, p, out)
+		}
+	}
+}
+
+// ConfigError is a problem with one value of a Config.
+type ConfigError struct {
+	Path    string // Field path, e.g. country_to_language_map.CH[1]
+	Source  string // Layer that set the value, for configs built by MergeConfigs
+	Line    int    // Line in the YAML source, or 0 if unknown
+	Column  int    // Column in the YAML source, or 0 if unknown
+	Message string // What is wrong with the value
+}
+
+func (e *ConfigError) Error() string {
+	var where []string
+	if e.Source != "" {
+		where = append(where, e.Source)
+	}
+	if e.Line > 0 {
+		where = append(where, fmt.Sprintf("line %d, column %d", e.Line, e.Column))
+	}
+	if len(where) == 0 {
+		return e.Path + ": " + e.Message
+	}
+	return e.Path + " (" + strings.Join(where, ", ") + "): " + e.Message
+}
+
+// ConfigErrors lists every problem found by Config.Validate.
+type ConfigErrors []*ConfigError
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "geolocation: invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual errors, for use with errors.As.
+func (e ConfigErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate checks the config and returns all problems at once as ConfigErrors, or nil.
+// For configs loaded from YAML, each problem carries the line and column of the value,
+// and for merged configs the layer that set it.
+//
+// It reports an empty or invalid default_language, an invalid cookie_name, country keys that are
+// not uppercase ISO 3166-1 alpha-2 codes, empty, invalid or duplicate languages, and invalid
+// geo_fence rules. Unknown fields are rejected when loading with WithStrict.
+//
+// Example:
+//
+//	var errs geolocation.ConfigErrors
+//	if errors.As(cfg.Validate(), &errs) {
+//		for _, e := range errs {
+//			log.Printf("%s:%d:%d: %s", path, e.Line, e.Column, e.Message)
+//		}
+//	}
+func (c *Config) Validate() error {
+	c = c.view()
+	var errs ConfigErrors
+	add := func(path, format string, args ...any) {
+		e := &ConfigError{Path: path, Source: c.Source(path), Message: fmt.Sprintf(format, args...)}
+		if p, ok := c.positions[path]; ok {
+			e.Line, e.Column = p.line, p.column
+		}
+		errs = append(errs, e)
+	}
+
+	switch {
+	case c.DefaultLanguage == "":
+		add("default_language", "required")
+	case !IsLanguageTag(c.DefaultLanguage):
+		add("default_language", "invalid language tag %q", c.DefaultLanguage)
+	}
+	if c.CookieName != "" && !isCookieName(c.CookieName) {
+		add("cookie_name", "invalid cookie name %q", c.CookieName)
+	}
+
+	countries := make([]string, 0, len(c.CountryToLanguageMap))
+	for country := range c.CountryToLanguageMap {
+		countries = append(countries, country)
+	}
+	slices.Sort(countries)
+	for _, country := range countries {
+		path := "country_to_language_map." + country
+		if !isCountryCode(country) {
+			if upper := strings.ToUpper(strings.TrimSpace(country)); isCountryCode(upper) {
+				add(path, "country code must be uppercase, use %q", upper)
+			} else {
+				add(path, "invalid country code %q", country)
+			}
+		}
+		langs := c.CountryToLanguageMap[country]
+		if len(langs) == 0 {
+			add(path, "no languages")
+		}
+		for i, lang := range langs {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case !IsLanguageTag(lang):
+				add(p, "invalid language tag %q", lang)
+			case slices.Contains(langs[:i], lang):
+				add(p, "duplicate language %q", lang)
+			}
+		}
+	}
+
+	if f := c.GeoFence; f != nil {
+		if err := f.Response.validate(); err != nil {
+			add("geo_fence.response", "%v", err)
+		}
+		for i := range f.Rules {
+			path := "geo_fence.rules[" + strconv.Itoa(i) + "]"
+			if _, err := compileFenceRule(&f.Rules[i]); err != nil {
+				add(path, "%v", err)
+			}
+			if r := f.Rules[i].Response; r != nil {
+				if err := r.validate(); err != nil {
+					add(path+".response", "%v", err)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// normalize trims and uppercases country keys. Keys that collide after normalization are an error.
+func (c *Config) normalize() error {
+	if len(c.CountryToLanguageMap) == 0 {
+		return nil
+	}
+	normalized := make(map[string][]string, len(c.CountryToLanguageMap))
+	for country, langs := range c.CountryToLanguageMap {
+		key := strings.ToUpper(strings.TrimSpace(country))
+		if _, ok := normalized[key]; ok {
+			return fmt.Errorf("geolocation: invalid config: country_to_language_map: duplicate country %q", key)
+		}
+		normalized[key] = langs
+		if key == country {
+			continue
+		}
+		// Keep source positions under the normalized path.
+		prefix := "country_to_language_map." + country
+		for path, p := range c.positions {
+			if hasPathPrefix(path, prefix) {
+				delete(c.positions, path)
+				c.positions["country_to_language_map."+key+path[len(prefix):]] = p
+			}
+		}
+	}
+	c.CountryToLanguageMap = normalized
+	return nil
+}
+
+// isCountryCode reports whether s is two uppercase ASCII letters.
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
+}
+
+// isCookieName reports whether s is a valid cookie name (an RFC 7230 token).
+func isCookieName(s string) bool {
+	for _, c := range []byte(s) {
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?={}`, c) >= 0 {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,300 @@
+package geolocation
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// writeConfig writes data to a temporary file with the given name and returns its path.
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfig_Validate(t *testing.T) {
+	valid := &Config{
+		DefaultLanguage:      "en",
+		CookieName:           "site_lang",
+		CountryToLanguageMap: map[string][]string{"CH": {"de", "fr", "it"}, "BR": {"pt-BR"}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+
+	cfg := &Config{
+		CookieName: "site lang",
+		CountryToLanguageMap: map[string][]string{
+			"ch":  {"de", "f_r", "de"},
+			"XYZ": {"en"},
+			"US":  {},
+		},
+		GeoFence: &GeoFenceConfig{Rules: []FenceRule{
+			{Mode: "block", Countries: []string{"CU"}},
+			{Mode: FenceDeny, Countries: []string{"CU"}, Response: &FenceResponse{Status: 200}},
+		}},
+	}
+	err := cfg.Validate()
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ConfigErrors, got %v", err)
+	}
+	var got []string
+	for _, e := range errs {
+		if e.Line != 0 {
+			t.Errorf("expected no position without a YAML source, got %+v", e)
+		}
+		got = append(got, e.Error())
+	}
+	want := []string{
+		`default_language: required`,
+		`cookie_name: invalid cookie name "site lang"`,
+		`country_to_language_map.US: no languages`,
+		`country_to_language_map.XYZ: invalid country code "XYZ"`,
+		`country_to_language_map.ch: country code must be uppercase, use "CH"`,
+		`country_to_language_map.ch[1]: invalid language tag "f_r"`,
+		`country_to_language_map.ch[2]: duplicate language "de"`,
+		`geo_fence.rules[0]: mode must be "allow" or "deny", got "block"`,
+		`geo_fence.rules[1].response: invalid status 200`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected errors:\n got: %q\nwant: %q", got, want)
+	}
+	if !strings.HasPrefix(err.Error(), "geolocation: invalid config: default_language: required; cookie_name") {
+		t.Errorf("unexpected message %q", err)
+	}
+}
+
+func TestConfig_ValidateYAMLPositions(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `default_language: ""
+cookie_name: geo_lang
+country_to_language_map:
+  CH: [de, f_r]
+  bg:
+    - bg
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	var errs ConfigErrors
+	if !errors.As(cfg.Validate(), &errs) || len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", cfg.Validate())
+	}
+	want := []ConfigError{
+		{Path: "default_language", Line: 1, Column: 1, Message: "required"},
+		{Path: "country_to_language_map.CH[1]", Line: 4, Column: 12, Message: `invalid language tag "f_r"`},
+		{Path: "country_to_language_map.bg", Line: 5, Column: 3, Message: `country code must be uppercase, use "BG"`},
+	}
+	for i, e := range errs {
+		if *e != want[i] {
+			t.Errorf("error %d = %+v, want %+v", i, *e, want[i])
+		}
+	}
+}
+
+func TestLoadConfig_Strict(t *testing.T) {
+	yamlPath := writeConfig(t, "config.yaml", `default_language: en
+cookie_name: geo_lang
+country_to_language_map:
+  ch: [de, fr]
+  " be ": [nl, fr]
+`)
+	cfg, err := LoadConfig(yamlPath, WithStrict())
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.CountryToLanguageMap, map[string][]string{"CH": {"de", "fr"}, "BE": {"nl", "fr"}}) {
+		t.Errorf("expected normalized keys, got %v", cfg.CountryToLanguageMap)
+	}
+	if cfg.ActiveLanguage("CH") != "de" {
+		t.Errorf("expected lookups to use normalized keys")
+	}
+
+	// Without strict mode keys are kept as written.
+	cfg, _ = LoadConfig(yamlPath)
+	if _, ok := cfg.CountryToLanguageMap["ch"]; !ok {
+		t.Errorf("expected keys unchanged without strict mode, got %v", cfg.CountryToLanguageMap)
+	}
+
+	tests := map[string]string{
+		"unknown json field": writeConfig(t, "c.json", `{"default_language":"en","cookie_nam":"geo_lang"}`),
+		"unknown yaml field": writeConfig(t, "c.yaml", "default_language: en\ncookie_nam: geo_lang\n"),
+		"unknown nested":     writeConfig(t, "d.yaml", "default_language: en\ngeo_fence:\n  rule: []\n"),
+		"duplicate country":  writeConfig(t, "e.json", `{"default_language":"en","country_to_language_map":{"CH":["de"],"ch":["fr"]}}`),
+		"invalid values":     writeConfig(t, "f.yaml", "default_language: en\ncountry_to_language_map:\n  ch: [\"\"]\n"),
+		"trailing data":      writeConfig(t, "g.json", `{"default_language":"en"} {}`),
+	}
+	for name, path := range tests {
+		if _, err := LoadConfig(path); err != nil && name != "trailing data" {
+			t.Errorf("%s: expected lenient load to succeed, got %v", name, err)
+		}
+		if _, err := LoadConfig(path, WithStrict()); err == nil {
+			t.Errorf("%s: expected strict load to fail", name)
+		}
+	}
+
+	_, err = LoadConfig(tests["unknown yaml field"], WithStrict())
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "cookie_nam") {
+		t.Errorf("expected positioned unknown field error, got %v", err)
+	}
+	_, err = LoadConfig(tests["invalid values"], WithStrict())
+	if err == nil || !strings.Contains(err.Error(), `country_to_language_map.CH[0] (line 3, column 8): invalid language tag ""`) {
+		t.Errorf("expected error at normalized path with source position, got %v", err)
+	}
+}
+
+func TestLoadConfigReader(t *testing.T) {
+	cfg, err := LoadConfigReader(strings.NewReader("default_language: en\ncountry_to_language_map:\n  CH: [de]\n"), FormatYAML)
+	if err != nil || cfg.DefaultLanguage != "en" || cfg.ActiveLanguage("CH") != "de" {
+		t.Errorf("unexpected result %+v, %v", cfg, err)
+	}
+	cfg, err = LoadConfigReader(strings.NewReader(`{"default_language":"fr"}`), FormatJSON)
+	if err != nil || cfg.DefaultLanguage != "fr" {
+		t.Errorf("unexpected result %+v, %v", cfg, err)
+	}
+	if _, err := LoadConfigReader(strings.NewReader(`{"default_language":"fr","x":1}`), FormatJSON, WithStrict()); err == nil {
+		t.Error("expected strict mode to apply")
+	}
+	if _, err := LoadConfigReader(strings.NewReader("{}"), "ini"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestLoadConfigFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/app.yml":  {Data: []byte("default_language: de\ncookie_name: lang\n")},
+		"config/app.json": {Data: []byte(`{"default_language":"bg"}`)},
+		"config/app.ini":  {Data: []byte("default_language=en")},
+	}
+	cfg, err := LoadConfigFS(fsys, "config/app.yml")
+	if err != nil || cfg.DefaultLanguage != "de" || cfg.CookieName != "lang" {
+		t.Errorf("unexpected result %+v, %v", cfg, err)
+	}
+	cfg, err = LoadConfigFS(fsys, "config/app.json")
+	if err != nil || cfg.DefaultLanguage != "bg" {
+		t.Errorf("unexpected result %+v, %v", cfg, err)
+	}
+	if _, err := LoadConfigFS(fsys, "config/app.ini"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+	if _, err := LoadConfigFS(fsys, "missing.yaml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+// Equivalent configs in every supported format, covering all sections.
+var formatFixtures = map[string]string{
+	"config.json": `{
+  "default_language": "en",
+  "cookie_name": "geo_lang",
+  "country_to_language_map": {"CA": ["en", "fr"], "CH": ["de", "fr", "it"]},
+  "geo_fence": {
+    "response": {"status": 451, "json": {"error": "unavailable", "retry": false}},
+    "rules": [
+      {"mode": "deny", "countries": ["CU", "IR"]},
+      {"paths": ["/eu/*"], "mode": "allow", "continents": ["EU"], "response": {"redirect": "/global"}}
+    ]
+  }
+}`,
+	"config.yaml": `default_language: en
+cookie_name: geo_lang
+country_to_language_map:
+  CA: [en, fr]
+  CH: [de, fr, it]
+geo_fence:
+  response:
+    status: 451
+    json: {error: unavailable, retry: false}
+  rules:
+    - mode: deny
+      countries: [CU, IR]
+    - paths: ["/eu/*"]
+      mode: allow
+      continents: [EU]
+      response:
+        redirect: /global
+`,
+	"config.toml": `default_language = "en"
+cookie_name = "geo_lang"
+
+[country_to_language_map]
+CA = ["en", "fr"]
+CH = ["de", "fr", "it"]
+
+[geo_fence.response]
+status = 451
+json = { error = "unavailable", retry = false }
+
+[[geo_fence.rules]]
+mode = "deny"
+countries = ["CU", "IR"]
+
+[[geo_fence.rules]]
+paths = ["/eu/*"]
+mode = "allow"
+continents = ["EU"]
+response = { redirect = "/global" }
+`,
+}
+
+func TestLoadConfig_FormatsDecodeIdentically(t *testing.T) {
+	want := &Config{
+		DefaultLanguage:      "en",
+		CookieName:           "geo_lang",
+		CountryToLanguageMap: map[string][]string{"CA": {"en", "fr"}, "CH": {"de", "fr", "it"}},
+		GeoFence: &GeoFenceConfig{
+			Response: FenceResponse{Status: 451, JSON: map[string]any{"error": "unavailable", "retry": false}},
+			Rules: []FenceRule{
+				{Mode: FenceDeny, Countries: []string{"CU", "IR"}},
+				{Paths: []string{"/eu/*"}, Mode: FenceAllow, Continents: []string{"EU"}, Response: &FenceResponse{Redirect: "/global"}},
+			},
+		},
+	}
+	for name, data := range formatFixtures {
+		for _, opts := range [][]LoadOption{nil, {WithStrict()}} {
+			cfg, err := LoadConfig(writeConfig(t, name, data), opts...)
+			if err != nil {
+				t.Errorf("%s: LoadConfig failed: %v", name, err)
+				continue
+			}
+			cfg.positions = nil
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("%s: decoded to\n%#v\nwant\n%#v", name, cfg, want)
+			}
+		}
+		if _, err := LoadGeoFence(writeConfig(t, name, data)); err != nil {
+			t.Errorf("%s: LoadGeoFence failed: %v", name, err)
+		}
+	}
+}
+
+func TestLoadConfig_TOML(t *testing.T) {
+	cfg, err := LoadConfigReader(strings.NewReader("default_language = \"en\"\n[country_to_language_map]\nch = [\"de\"]\n"), FormatTOML, WithStrict())
+	if err != nil || cfg.ActiveLanguage("CH") != "de" {
+		t.Errorf("expected normalized TOML config, got %+v, %v", cfg, err)
+	}
+
+	unknown := writeConfig(t, "unknown.toml", "default_language = \"en\"\ncookie_nam = \"lang\"\n")
+	if _, err := LoadConfig(unknown); err != nil {
+		t.Errorf("expected lenient load to succeed, got %v", err)
+	}
+	if _, err := LoadConfig(unknown, WithStrict()); err == nil || !strings.Contains(err.Error(), "cookie_nam (line 2, column 1): unknown field") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	if _, err := LoadConfig(writeConfig(t, "bad.toml", "default_language = en\n")); err == nil || !strings.Contains(err.Error(), "line 1, column 21") {
+		t.Errorf("expected positioned error for invalid TOML, got %v", err)
+	}
+	if format, err := FormatFromPath("/etc/app/geo.toml"); err != nil || format != FormatTOML {
+		t.Errorf("FormatFromPath = %q, %v", format, err)
+	}
+}
@@ -0,0 +1,250 @@
+package geolocation
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// compiledConfig holds the values and lookups of a compiled Config. It is built once and never modified.
+type compiledConfig struct {
+	fields    *Config             // Copy of the fields at compile time, read instead of them; see Config.view
+	countries map[string][]string // Uppercase country code -> languages
+	languages []string            // See Config.Languages
+}
+
+// NewConfig returns a compiled copy of cfg. Country codes are trimmed and uppercased, and the
+// result must pass Validate. The copy shares nothing with cfg, so cfg may be reused or changed.
+//
+// Example:
+//
+//	cfg, err := geolocation.NewConfig(geolocation.Config{
+//		DefaultLanguage:      "en",
+//		CookieName:           "site_lang",
+//		CountryToLanguageMap: map[string][]string{"ch": {"de", "fr", "it"}},
+//	})
+//	langs := cfg.ActiveLanguages("CH") // [de fr it], a copy
+func NewConfig(cfg Config) (*Config, error) {
+	c := cfg.clone()
+	if err := c.normalize(); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	c.compile()
+	return c, nil
+}
+
+// ConfigBuilder builds compiled configs value by value. The zero value is an empty builder.
+//
+// Example:
+//
+//	cfg, err := geolocation.NewConfigBuilder().
+//		DefaultLanguage("en").
+//		CookieName("site_lang").
+//		Country("CH", "de", "fr", "it").
+//		Country("BE", "nl", "fr").
+//		Build()
+type ConfigBuilder struct {
+	cfg Config
+}
+
+// NewConfigBuilder returns an empty ConfigBuilder.
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{}
+}
+
+// Builder returns a ConfigBuilder starting from the values of c, to derive a changed config.
+//
+// Example:
+//
+//	updated, err := cfg.Builder().Country("CH", "de", "fr").Build()
+func (c *Config) Builder() *ConfigBuilder {
+	return &ConfigBuilder{cfg: *c.clone()}
+}
+
+// DefaultLanguage sets the default language.
+func (b *ConfigBuilder) DefaultLanguage(lang string) *ConfigBuilder {
+	b.cfg.DefaultLanguage = lang
+	b.changed("default_language")
+	return b
+}
+
+// CookieName sets the name of the language cookie.
+func (b *ConfigBuilder) CookieName(name string) *ConfigBuilder {
+	b.cfg.CookieName = name
+	b.changed("cookie_name")
+	return b
+}
+
+// Country sets the languages of a country, replacing those set before.
+func (b *ConfigBuilder) Country(code string, languages ...string) *ConfigBuilder {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if b.cfg.CountryToLanguageMap == nil {
+		b.cfg.CountryToLanguageMap = map[string][]string{}
+	}
+	b.cfg.CountryToLanguageMap[code] = slices.Clone(languages)
+	b.changed("country_to_language_map." + code)
+	return b
+}
+
+// RemoveCountry removes a country, so that it falls back to the default language.
+func (b *ConfigBuilder) RemoveCountry(code string) *ConfigBuilder {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	delete(b.cfg.CountryToLanguageMap, code)
+	b.changed("country_to_language_map." + code)
+	return b
+}
+
+// GeoFence sets the geo fence section; nil removes it. The builder keeps a copy of fence.
+func (b *ConfigBuilder) GeoFence(fence *GeoFenceConfig) *ConfigBuilder {
+	b.cfg.GeoFence = cloneGeoFenceConfig(fence)
+	b.changed("geo_fence")
+	return b
+}
+
+// Build returns a compiled config with the builder's values, as NewConfig does.
+// The builder can be changed and built again afterwards.
+func (b *ConfigBuilder) Build() (*Config, error) {
+	return NewConfig(b.cfg)
+}
+
+// changed forgets the source position and layer of the value at path, which the builder replaced.
+func (b *ConfigBuilder) changed(path string) {
+	maps.DeleteFunc(b.cfg.positions, func(p string, _ position) bool { return hasPathPrefix(p, path) })
+	delete(b.cfg.sources, path)
+}
+
+// Languages returns every language of the config: the default language, then the languages of
+// each country in order of country code, without duplicates. The result is a copy.
+func (c *Config) Languages() []string {
+	if c.compiled != nil {
+		return slices.Clone(c.compiled.languages)
+	}
+	return siteLanguages(c.DefaultLanguage, c.CountryToLanguageMap)
+}
+
+// Countries returns the country codes the config maps to languages, sorted.
+func (c *Config) Countries() []string {
+	return slices.Sorted(maps.Keys(c.countries()))
+}
+
+// compile snapshots the current fields of c and builds its lookups. It must be called before c is
+// shared. Country codes are uppercased; of keys that differ only in case, the uppercase one wins.
+func (c *Config) compile() {
+	fields := c.clone()
+	cc := &compiledConfig{
+		fields:    fields,
+		countries: make(map[string][]string, len(fields.CountryToLanguageMap)),
+	}
+	for country, langs := range fields.CountryToLanguageMap {
+		key := strings.ToUpper(strings.TrimSpace(country))
+		if _, ok := cc.countries[key]; ok && key != country {
+			continue
+		}
+		cc.countries[key] = slices.Clip(slices.Clone(langs))
+	}
+	cc.languages = siteLanguages(fields.DefaultLanguage, cc.countries)
+	c.compiled = cc
+}
+
+// countries returns the country map used for lookups.
+func (c *Config) countries() map[string][]string {
+	if c.compiled != nil {
+		return c.compiled.countries
+	}
+	return c.CountryToLanguageMap
+}
+
+// countryLanguages returns the languages mapped to country, without copying them.
+func (c *Config) countryLanguages(country string) ([]string, bool) {
+	if c.compiled != nil {
+		langs, ok := c.compiled.countries[strings.ToUpper(country)]
+		return langs, ok
+	}
+	if langs, ok := c.CountryToLanguageMap[country]; ok {
+		return langs, true
+	}
+	// Match like compiled configs, whose keys are uppercased.
+	if langs, ok := c.CountryToLanguageMap[strings.ToUpper(country)]; ok {
+		return langs, true
+	}
+	for key, langs := range c.CountryToLanguageMap {
+		if strings.EqualFold(strings.TrimSpace(key), country) {
+			return langs, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Config) defaultLanguage() string {
+	return c.view().DefaultLanguage
+}
+
+// view returns the config whose fields are read: for compiled configs the copy made by compile,
+// so that changing the exported fields afterwards has no effect, otherwise c itself.
+func (c *Config) view() *Config {
+	if c.compiled != nil {
+		return c.compiled.fields
+	}
+	return c
+}
+
+// clone returns a deep copy of the values of c that is not compiled.
+func (c *Config) clone() *Config {
+	c = c.view()
+	out := &Config{
+		DefaultLanguage: c.DefaultLanguage,
+		CookieName:      c.CookieName,
+		GeoFence:        cloneGeoFenceConfig(c.GeoFence),
+		positions:       maps.Clone(c.positions),
+		sources:         maps.Clone(c.sources),
+	}
+	if c.CountryToLanguageMap != nil {
+		out.CountryToLanguageMap = make(map[string][]string, len(c.CountryToLanguageMap))
+		for country, langs := range c.CountryToLanguageMap {
+			out.CountryToLanguageMap[country] = slices.Clone(langs)
+		}
+	}
+	return out
+}
+
+// cloneGeoFenceConfig returns a deep copy of g. JSON response bodies are shared.
+func cloneGeoFenceConfig(g *GeoFenceConfig) *GeoFenceConfig {
+	if g == nil {
+		return nil
+	}
+	out := &GeoFenceConfig{Rules: slices.Clone(g.Rules), Response: g.Response}
+	for i := range out.Rules {
+		r := &out.Rules[i]
+		r.Paths = slices.Clone(r.Paths)
+		r.Countries = slices.Clone(r.Countries)
+		r.Regions = slices.Clone(r.Regions)
+		r.Continents = slices.Clone(r.Continents)
+		if r.Response != nil {
+			resp := *r.Response
+			r.Response = &resp
+		}
+	}
+	return out
+}
+
+// siteLanguages lists the default language and the languages of each country, without duplicates.
+func siteLanguages(defaultLanguage string, countries map[string][]string) []string {
+	var langs []string
+	seen := map[string]bool{}
+	add := func(lang string) {
+		if key := strings.ToLower(lang); lang != "" && !seen[key] {
+			seen[key] = true
+			langs = append(langs, lang)
+		}
+	}
+	add(defaultLanguage)
+	for _, country := range slices.Sorted(maps.Keys(countries)) {
+		for _, lang := range countries[country] {
+			add(lang)
+		}
+	}
+	return langs
+}
@@ -0,0 +1,296 @@
+package geolocation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewConfig(t *testing.T) {
+	src := Config{
+		DefaultLanguage:      "en",
+		CookieName:           "site_lang",
+		CountryToLanguageMap: map[string][]string{" ch": {"de", "fr", "it"}, "BR": {"pt-BR"}},
+		GeoFence:             &GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CU"}}}},
+	}
+	cfg, err := NewConfig(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Countries(); !reflect.DeepEqual(got, []string{"BR", "CH"}) {
+		t.Errorf("expected normalized countries, got %v", got)
+	}
+	for _, country := range []string{"CH", "ch"} {
+		if got := cfg.ActiveLanguages(country); !reflect.DeepEqual(got, []string{"de", "fr", "it"}) {
+			t.Errorf("ActiveLanguages(%q) = %v", country, got)
+		}
+	}
+
+	// The compiled config shares nothing with its source.
+	src.CountryToLanguageMap[" ch"][0] = "xx"
+	src.GeoFence.Rules[0].Countries[0] = "XX"
+	if cfg.ActiveLanguage("CH") != "de" || cfg.GeoFence.Rules[0].Countries[0] != "CU" {
+		t.Error("expected NewConfig to copy its input")
+	}
+
+	if _, err := NewConfig(Config{CountryToLanguageMap: map[string][]string{"CH": {"d_e"}}}); err == nil {
+		t.Error("expected validation error")
+	} else if !errors.As(err, new(ConfigErrors)) {
+		t.Errorf("expected ConfigErrors, got %T", err)
+	}
+	if _, err := NewConfig(Config{DefaultLanguage: "en", CountryToLanguageMap: map[string][]string{"ch": {"de"}, "CH": {"fr"}}}); err == nil {
+		t.Error("expected error for duplicate country")
+	}
+}
+
+func TestConfig_DefensiveCopies(t *testing.T) {
+	cfg, err := NewConfigBuilder().DefaultLanguage("en").Country("CA", "en", "fr").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	langs := cfg.ActiveLanguages("CA")
+	langs[0] = "xx"
+	_ = append(langs, "yy")
+	cfg.Languages()[0] = "xx"
+	cfg.ActiveLanguages("US")[0] = "xx"
+	if got := cfg.ActiveLanguages("CA"); !reflect.DeepEqual(got, []string{"en", "fr"}) {
+		t.Errorf("ActiveLanguages changed to %v", got)
+	}
+	if got := cfg.Languages(); !reflect.DeepEqual(got, []string{"en", "fr"}) {
+		t.Errorf("Languages changed to %v", got)
+	}
+
+	// Struct literals are not compiled and still copy their results.
+	lit := &Config{DefaultLanguage: "en", CountryToLanguageMap: map[string][]string{"CA": {"en", "fr"}}}
+	lit.ActiveLanguages("CA")[0] = "xx"
+	if lit.CountryToLanguageMap["CA"][0] != "en" {
+		t.Error("expected ActiveLanguages to return a copy")
+	}
+	if got := lit.Languages(); !reflect.DeepEqual(got, []string{"en", "fr"}) {
+		t.Errorf("Languages = %v", got)
+	}
+}
+
+// TestConfig_FieldsAfterCompile changes every field of a compiled config and checks that nothing
+// reading the config sees the change.
+func TestConfig_FieldsAfterCompile(t *testing.T) {
+	cfg, err := NewConfigBuilder().
+		DefaultLanguage("en").
+		CookieName("site_lang").
+		Country("CA", "en", "fr").
+		GeoFence(&GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CU"}}}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := cfg.Values()
+
+	cfg.DefaultLanguage = "d_e"
+	cfg.CookieName = "bad cookie"
+	cfg.CountryToLanguageMap["CA"][0] = "xx"
+	cfg.CountryToLanguageMap["US"] = []string{"es"}
+	cfg.GeoFence.Rules[0].Countries[0] = "XX"
+	cfg.GeoFence = nil
+
+	if cfg.ActiveLanguage("CA") != "en" || cfg.ActiveLanguage("US") != "en" {
+		t.Errorf("expected compiled lookups, got %q %q", cfg.ActiveLanguage("CA"), cfg.ActiveLanguage("US"))
+	}
+	if got := cfg.Languages(); !reflect.DeepEqual(got, []string{"en", "fr"}) {
+		t.Errorf("Languages = %v", got)
+	}
+	if got := cfg.Values(); !reflect.DeepEqual(got, values) {
+		t.Errorf("Values = %v, want %v", got, values)
+	}
+	cfg.Values()[3].Value.(*GeoFenceConfig).Rules[0].Countries[0] = "XX"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+	if rebuilt, err := cfg.Builder().Build(); err != nil || len(DiffConfigs(cfg, rebuilt)) != 0 {
+		t.Errorf("expected Builder to start from the compiled values, got %v", err)
+	}
+
+	switcher, err := NewLanguageSwitcher(cfg)
+	if err != nil || switcher.CookieName != "site_lang" {
+		t.Errorf("NewLanguageSwitcher = %v, %v", switcher, err)
+	}
+	var cookie string
+	h := Middleware(WithConfig(cfg))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie = RequestInfoFromContext(r.Context()).LanguageInfo().Default
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "site_lang", Value: "fr"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if cookie != "fr" {
+		t.Errorf("expected the middleware to read the compiled cookie name, got %q", cookie)
+	}
+
+	merged, err := MergeConfigs(ConfigLayer{Name: "base", Config: cfg})
+	if err != nil || merged.ActiveLanguage("US") != "en" || merged.GeoFence == nil {
+		t.Errorf("expected MergeConfigs to read the compiled values, got %v %v", merged.Values(), err)
+	}
+}
+
+func TestConfigBuilder(t *testing.T) {
+	b := NewConfigBuilder().
+		DefaultLanguage("en").
+		CookieName("site_lang").
+		Country("ch", "de", "fr", "it").
+		Country("BE", "nl", "fr").
+		Country("US", "en")
+	cfg, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Languages(); !reflect.DeepEqual(got, []string{"en", "nl", "fr", "de", "it"}) {
+		t.Errorf("Languages = %v", got)
+	}
+
+	// The builder can be reused without affecting configs built before.
+	again, err := b.RemoveCountry("us").Country("CH", "it").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ActiveLanguage("CH") != "de" || again.ActiveLanguage("CH") != "it" || !reflect.DeepEqual(again.Countries(), []string{"BE", "CH"}) {
+		t.Errorf("unexpected rebuilt config %v", again.Values())
+	}
+
+	if _, err := NewConfigBuilder().Country("CH", "de").Build(); err == nil {
+		t.Error("expected error without default language")
+	}
+}
+
+func TestConfig_Builder(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "default_language: en\ncountry_to_language_map:\n  CH: [de, fr]\n  US: [en]\n")
+	base, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fence := &GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CU"}}}}
+	_, err = base.Builder().Country("CH", "d_e").GeoFence(fence).Build()
+	var errs ConfigErrors
+	if !errors.As(err, &errs) || errs[0].Line != 0 {
+		t.Errorf("expected error without the replaced value's position, got %v", err)
+	}
+	cfg, err := base.Builder().Country("CH", "it").GeoFence(fence).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fence.Rules[0].Countries[0] = "IR"
+	if cfg.ActiveLanguage("CH") != "it" || cfg.ActiveLanguage("US") != "en" || cfg.GeoFence.Rules[0].Countries[0] != "CU" {
+		t.Errorf("unexpected derived config %v", cfg.Values())
+	}
+	if base.ActiveLanguage("CH") != "de" || base.GeoFence != nil {
+		t.Error("expected base config to stay unchanged")
+	}
+}
+
+func TestLoadConfig_FieldsHonoured(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "config.json", `{"default_language":"en","country_to_language_map":{"ch":["de"]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ActiveLanguage("CH") != "de" || cfg.ActiveLanguage("ch") != "de" {
+		t.Error("expected loaded configs to match country codes case-insensitively")
+	}
+	if GetLanguageForCountry(httptest.NewRequest("GET", "/", nil), cfg, "ch", []string{"de"}) != "de" {
+		t.Error("expected GetLanguageForCountry to match country codes case-insensitively")
+	}
+
+	// Loaded configs are not compiled, so adjusting them takes effect.
+	cfg.DefaultLanguage = "fr"
+	cfg.CountryToLanguageMap["BE"] = []string{"nl"}
+	if got := cfg.ActiveLanguages("XX"); !reflect.DeepEqual(got, []string{"fr"}) {
+		t.Errorf("ActiveLanguages(XX) = %v, want [fr]", got)
+	}
+	if got := cfg.ActiveLanguages("BE"); !reflect.DeepEqual(got, []string{"nl"}) {
+		t.Errorf("ActiveLanguages(BE) = %v, want [nl]", got)
+	}
+	if got := cfg.Languages(); !reflect.DeepEqual(got, []string{"fr", "nl", "de"}) {
+		t.Errorf("Languages = %v", got)
+	}
+
+	// Struct literals match like compiled configs.
+	lit := &Config{DefaultLanguage: "en", CountryToLanguageMap: map[string][]string{"ch": {"de"}}}
+	compiled, err := NewConfig(*lit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, country := range []string{"CH", "ch", "Ch"} {
+		if lit.ActiveLanguage(country) != "de" || compiled.ActiveLanguage(country) != "de" {
+			t.Errorf("ActiveLanguage(%q) = %q literal, %q compiled", country, lit.ActiveLanguage(country), compiled.ActiveLanguage(country))
+		}
+	}
+}
+
+// TestConfig_ConcurrentReload reads configs from many goroutines, modifying every result,
+// while a ConfigWatcher reloads. Run with -race.
+func TestConfig_ConcurrentReload(t *testing.T) {
+	versions := []string{
+		"default_language: en\ncookie_name: site_lang\ncountry_to_language_map:\n  CH: [de, fr, it]\n  BE: [nl, fr]\n",
+		"default_language: en\ncookie_name: site_lang\ncountry_to_language_map:\n  CH: [fr, de, it]\n  BE: [fr, nl]\n",
+	}
+	path := writeConfig(t, "config.yaml", versions[0])
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Middleware(WithConfig(w))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		info := RequestInfoFromContext(r.Context())
+		if lang := info.SiteLanguage("de", "fr"); lang != info.Config().ActiveLanguage("CH") {
+			t.Errorf("site language %q does not match the request's config", lang)
+		}
+	}))
+
+	const readers = 16
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				cfg := w.Config()
+				langs := cfg.ActiveLanguages("CH")
+				if len(langs) != 3 || langs[2] != "it" {
+					t.Errorf("reader %d: corrupted languages %v", i, langs)
+					return
+				}
+				langs[0] = fmt.Sprint("x", i)
+				_ = append(langs, "yy")
+				all := cfg.Languages()
+				all[0] = "xx"
+				_ = cfg.Values()
+				_ = GetLanguageForCountry(httptest.NewRequest("GET", "/", nil), w, "BE", []string{"fr", "nl"})
+				r := httptest.NewRequest("GET", "/", nil)
+				r.Header.Set("CF-IPCountry", "CH")
+				handler.ServeHTTP(httptest.NewRecorder(), r)
+			}
+		}()
+	}
+
+	for i := range 50 {
+		rewriteConfig(t, path, versions[(i+1)%2])
+		if err := w.Reload(); err != nil {
+			t.Error(err)
+		}
+		if _, err := w.Config().Builder().Country("US", "en").Build(); err != nil {
+			t.Error(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if got := strings.Join(w.Config().ActiveLanguages("CH"), ","); got != "de,fr,it" {
+		t.Errorf("final languages = %s", got)
+	}
+}
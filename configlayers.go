@@ -0,0 +1,243 @@
+package geolocation
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by ConfigFromEnv.
+const DefaultEnvPrefix = "GEO_"
+
+// ConfigFromEnv returns the configuration set by environment variables with the given prefix,
+// or DefaultEnvPrefix if empty. Unset and empty variables leave the corresponding fields empty,
+// so the result is meant to be merged over a file with MergeConfigs:
+//
+//	GEO_DEFAULT_LANGUAGE=en
+//	GEO_COOKIE_NAME=site_lang
+//	GEO_COUNTRY_MAP_CH=de,fr,it
+func ConfigFromEnv(prefix string) *Config {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	cfg := &Config{}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		switch name {
+		case "DEFAULT_LANGUAGE":
+			cfg.DefaultLanguage = strings.TrimSpace(value)
+		case "COOKIE_NAME":
+			cfg.CookieName = strings.TrimSpace(value)
+		default:
+			country, ok := strings.CutPrefix(name, "COUNTRY_MAP_")
+			if !ok || country == "" {
+				continue
+			}
+			var langs []string
+			for _, lang := range strings.Split(value, ",") {
+				if lang = strings.TrimSpace(lang); lang != "" {
+					langs = append(langs, lang)
+				}
+			}
+			if len(langs) == 0 {
+				continue
+			}
+			if cfg.CountryToLanguageMap == nil {
+				cfg.CountryToLanguageMap = map[string][]string{}
+			}
+			cfg.CountryToLanguageMap[strings.ToUpper(country)] = langs
+		}
+	}
+	return cfg
+}
+
+// ConfigLayer is one source of configuration for MergeConfigs.
+type ConfigLayer struct {
+	Name   string  // Reported as the source of the layer's values, e.g. a file name or "env"
+	Config *Config // Nil layers are skipped
+}
+
+// ConfigValue is one effective value of a Config and the layer it came from.
+type ConfigValue struct {
+	Path   string // Field path, e.g. default_language or country_to_language_map.CH
+	Value  any    // string, []string or *GeoFenceConfig
+	Source string // Name of the layer that set it; empty if the config was not merged
+}
+
+// MergeConfigs layers configs in order of increasing precedence: each layer overrides the values
+// set by the layers before it. Empty strings and nil fields do not override. Country languages are
+// merged per country, so a layer replaces the languages of the countries it lists and keeps the
+// others; country keys are normalized to uppercase. The geo_fence section is replaced as a whole.
+//
+// The result is a new Config whose values, and the layer each came from, are listed by Values.
+// Validation errors name the layer of the offending value.
+//
+// Example:
+//
+//	cfg, err := geolocation.MergeConfigs(
+//		geolocation.ConfigLayer{Name: "config.yaml", Config: base},
+//		geolocation.ConfigLayer{Name: "config.production.yaml", Config: prod},
+//		geolocation.ConfigLayer{Name: "env", Config: geolocation.ConfigFromEnv("")},
+//	)
+func MergeConfigs(layers ...ConfigLayer) (*Config, error) {
+	merged := &Config{positions: map[string]position{}, sources: map[string]string{}}
+	for _, layer := range layers {
+		c := layer.Config
+		if c == nil {
+			continue
+		}
+		c = c.view()
+		if len(c.CountryToLanguageMap) > 0 {
+			// Normalize a copy so that the layer's source positions follow the uppercase keys.
+			c = &Config{
+				DefaultLanguage:      c.DefaultLanguage,
+				CountryToLanguageMap: maps.Clone(c.CountryToLanguageMap),
+				CookieName:           c.CookieName,
+				GeoFence:             c.GeoFence,
+				positions:            maps.Clone(c.positions),
+			}
+			if err := c.normalize(); err != nil {
+				return nil, fmt.Errorf("%w in %s", err, layer.Name)
+			}
+		}
+		set := func(path string) {
+			merged.sources[path] = layer.Name
+			maps.DeleteFunc(merged.positions, func(p string, _ position) bool { return hasPathPrefix(p, path) })
+			for p, pos := range c.positions {
+				if hasPathPrefix(p, path) {
+					merged.positions[p] = pos
+				}
+			}
+		}
+		if c.DefaultLanguage != "" {
+			merged.DefaultLanguage = c.DefaultLanguage
+			set("default_language")
+		}
+		if c.CookieName != "" {
+			merged.CookieName = c.CookieName
+			set("cookie_name")
+		}
+		for country, langs := range c.CountryToLanguageMap {
+			if merged.CountryToLanguageMap == nil {
+				merged.CountryToLanguageMap = map[string][]string{}
+			}
+			merged.CountryToLanguageMap[country] = slices.Clone(langs)
+			set("country_to_language_map." + country)
+		}
+		if c.GeoFence != nil {
+			merged.GeoFence = cloneGeoFenceConfig(c.GeoFence)
+			set("geo_fence")
+		}
+	}
+	return merged, nil
+}
+
+// LoadLayeredConfig loads the first of paths, merges each further file that exists over it, and then
+// the environment variables with envPrefix (DefaultEnvPrefix if empty), as with MergeConfigs.
+// The first file is required; later ones, such as environment-specific overrides, are optional.
+//
+// With WithStrict, unknown fields are rejected in every file and the merged config is validated.
+//
+// Example:
+//
+//	cfg, err := geolocation.LoadLayeredConfig([]string{"config.yaml", "config." + env + ".yaml"}, "")
+//	for _, v := range cfg.Values() {
+//		log.Printf("%s = %v (from %s)", v.Path, v.Value, v.Source)
+//	}
+func LoadLayeredConfig(paths []string, envPrefix string, opts ...LoadOption) (*Config, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("geolocation: no config files")
+	}
+	o := newLoadOptions(opts)
+	layerOpts := o
+	layerOpts.partial = true
+	var layers []ConfigLayer
+	for i, path := range paths {
+		format, err := FormatFromPath(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if i > 0 && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg, err := decodeConfig(data, format, layerOpts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		layers = append(layers, ConfigLayer{Name: path, Config: cfg})
+	}
+	layers = append(layers, ConfigLayer{Name: "env", Config: ConfigFromEnv(envPrefix)})
+	cfg, err := MergeConfigs(layers...)
+	if err != nil {
+		return nil, err
+	}
+	if o.strict {
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Values returns the effective values of the config in a fixed order, with the layer each came from
+// when the config was built by MergeConfigs. Unset values are omitted.
+func (c *Config) Values() []ConfigValue {
+	var values []ConfigValue
+	add := func(path string, value any) {
+		values = append(values, ConfigValue{Path: path, Value: value, Source: c.sources[path]})
+	}
+	v := c.view()
+	if v.DefaultLanguage != "" {
+		add("default_language", v.DefaultLanguage)
+	}
+	if v.CookieName != "" {
+		add("cookie_name", v.CookieName)
+	}
+	countries := c.countries()
+	for _, country := range slices.Sorted(maps.Keys(countries)) {
+		add("country_to_language_map."+country, slices.Clone(countries[country]))
+	}
+	if v.GeoFence != nil {
+		if c.compiled != nil {
+			add("geo_fence", cloneGeoFenceConfig(v.GeoFence))
+		} else {
+			add("geo_fence", v.GeoFence)
+		}
+	}
+	return values
+}
+
+// Source returns the name of the layer that set the value at path, or of the value containing it,
+// e.g. for country_to_language_map.CH[1] the layer that set the languages of CH.
+// It returns an empty string for configs not built by MergeConfigs.
+func (c *Config) Source(path string) string {
+	for path != "" {
+		if s, ok := c.sources[path]; ok {
+			return s
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return ""
+}
+
+// hasPathPrefix reports whether path is prefix or a value below it.
+func hasPathPrefix(path, prefix string) bool {
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || rest[0] == '.' || rest[0] == '[')
+}
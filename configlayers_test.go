@@ -0,0 +1,146 @@
+package geolocation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("GEO_DEFAULT_LANGUAGE", " de ")
+	t.Setenv("GEO_COOKIE_NAME", "site_lang")
+	t.Setenv("GEO_COUNTRY_MAP_CH", "de, fr,,it")
+	t.Setenv("GEO_COUNTRY_MAP_be", "nl,fr")
+	t.Setenv("GEO_COUNTRY_MAP_", "en")
+	t.Setenv("GEO_COUNTRY_MAP_FR", " , ")
+	t.Setenv("GEO_OTHER", "x")
+	t.Setenv("APP_DEFAULT_LANGUAGE", "fr")
+
+	cfg := ConfigFromEnv("")
+	want := &Config{
+		DefaultLanguage:      "de",
+		CookieName:           "site_lang",
+		CountryToLanguageMap: map[string][]string{"CH": {"de", "fr", "it"}, "BE": {"nl", "fr"}},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("ConfigFromEnv = %+v, want %+v", cfg, want)
+	}
+	if cfg := ConfigFromEnv("APP_"); cfg.DefaultLanguage != "fr" || cfg.CountryToLanguageMap != nil {
+		t.Errorf("unexpected config for custom prefix: %+v", cfg)
+	}
+}
+
+func TestMergeConfigs(t *testing.T) {
+	base := &Config{
+		DefaultLanguage:      "en",
+		CookieName:           "lang",
+		CountryToLanguageMap: map[string][]string{"CH": {"de"}, "CA": {"en", "fr"}},
+		GeoFence:             &GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CU"}}}},
+	}
+	prod := &Config{
+		CookieName:           "site_lang",
+		CountryToLanguageMap: map[string][]string{"ch": {"de", "fr"}},
+	}
+	env := &Config{
+		DefaultLanguage:      "fr",
+		CountryToLanguageMap: map[string][]string{"BE": {"nl"}},
+	}
+	cfg, err := MergeConfigs(
+		ConfigLayer{Name: "base.yaml", Config: base},
+		ConfigLayer{Name: "skipped", Config: nil},
+		ConfigLayer{Name: "prod.yaml", Config: prod},
+		ConfigLayer{Name: "env", Config: env},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ConfigValue{
+		{"default_language", "fr", "env"},
+		{"cookie_name", "site_lang", "prod.yaml"},
+		{"country_to_language_map.BE", []string{"nl"}, "env"},
+		{"country_to_language_map.CA", []string{"en", "fr"}, "base.yaml"},
+		{"country_to_language_map.CH", []string{"de", "fr"}, "prod.yaml"},
+		{"geo_fence", base.GeoFence, "base.yaml"},
+	}
+	if got := cfg.Values(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() =\n%v\nwant\n%v", got, want)
+	}
+	if cfg.Source("country_to_language_map.CH[1]") != "prod.yaml" || cfg.Source("missing") != "" {
+		t.Errorf("unexpected sources")
+	}
+
+	// Layers are not modified and the result does not share their maps or slices.
+	cfg.CountryToLanguageMap["CA"][0] = "xx"
+	if base.CountryToLanguageMap["CA"][0] != "en" || len(prod.CountryToLanguageMap) != 1 || prod.CountryToLanguageMap["ch"] == nil {
+		t.Error("expected layers to be unchanged")
+	}
+
+	if _, err := MergeConfigs(ConfigLayer{Name: "bad", Config: &Config{CountryToLanguageMap: map[string][]string{"CH": {"de"}, "ch": {"fr"}}}}); err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("expected duplicate country error naming the layer, got %v", err)
+	}
+	if (&Config{DefaultLanguage: "en"}).Values()[0].Source != "" {
+		t.Error("expected no source for unmerged configs")
+	}
+}
+
+func TestLoadLayeredConfig(t *testing.T) {
+	base := writeConfig(t, "config.yaml", `default_language: en
+cookie_name: lang
+country_to_language_map:
+  CH: [de]
+  CA: [en, fr]
+`)
+	prod := writeConfig(t, "config.production.json", `{"country_to_language_map":{"ch":["de","f_r"]}}`)
+	t.Setenv("GEO_COUNTRY_MAP_BE", "nl,fr")
+	t.Setenv("GEO_COOKIE_NAME", "site_lang")
+
+	cfg, err := LoadLayeredConfig([]string{base, prod, base + ".missing.yaml"}, "")
+	if err != nil {
+		t.Fatalf("LoadLayeredConfig failed: %v", err)
+	}
+	sources := map[string]string{}
+	for _, v := range cfg.Values() {
+		sources[v.Path] = v.Source
+	}
+	want := map[string]string{
+		"default_language":           base,
+		"cookie_name":                "env",
+		"country_to_language_map.BE": "env",
+		"country_to_language_map.CA": base,
+		"country_to_language_map.CH": prod,
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("sources = %v, want %v", sources, want)
+	}
+
+	// Strict mode validates the merged config and reports where each problem came from.
+	_, err = LoadLayeredConfig([]string{base, prod}, "", WithStrict())
+	var errs ConfigErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Source != prod || errs[0].Path != "country_to_language_map.CH[1]" {
+		t.Errorf("expected error from the production layer, got %v", err)
+	}
+
+	// A partial overlay is valid in strict mode as long as the merged config is.
+	t.Setenv("GEO_COUNTRY_MAP_BE", "")
+	overlay := writeConfig(t, "config.staging.yaml", "country_to_language_map:\n  ch: [fr]\n")
+	cfg, err = LoadLayeredConfig([]string{base, overlay}, "", WithStrict())
+	if err != nil || cfg.ActiveLanguage("CH") != "fr" {
+		t.Errorf("unexpected result %v, %v", cfg, err)
+	}
+
+	// YAML positions of a merged value point into the layer that set it.
+	bad := writeConfig(t, "config.bad.yaml", "country_to_language_map:\n  CA:\n    - en\n    - \"\"\n")
+	_, err = LoadLayeredConfig([]string{base, bad}, "", WithStrict())
+	if !errors.As(err, &errs) || errs[0].Error() != `country_to_language_map.CA[1] (`+bad+`, line 4, column 7): invalid language tag ""` {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	if _, err := LoadLayeredConfig([]string{base + ".missing.yaml"}, ""); err == nil {
+		t.Error("expected error for missing base file")
+	}
+	if _, err := LoadLayeredConfig(nil, ""); err == nil {
+		t.Error("expected error without files")
+	}
+}
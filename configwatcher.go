@@ -0,0 +1,254 @@
+package geolocation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultConfigPollInterval is how often ConfigWatcher.Watch checks the file when no interval is given.
+const DefaultConfigPollInterval = 5 * time.Second
+
+// ConfigWatcher keeps a configuration file loaded and reloads it when it changes, so that country
+// mappings can be updated without a restart. Each reload is validated; a file that fails to load or
+// validate is reported and the previous configuration stays in effect.
+//
+// ConfigWatcher is a ConfigProvider: pass it to WithConfig and GetLanguageForCountry to always use
+// the current configuration. It is safe for concurrent use. Geo fences passed to WithGeoFence are
+// built once and are not reloaded.
+//
+// Example:
+//
+//	watcher, err := geolocation.NewConfigWatcher("config.yaml", geolocation.WithStrict())
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	watcher.OnError = func(err error) { log.Printf("config not reloaded: %v", err) }
+//	watcher.Subscribe(func(c geolocation.ConfigChange) {
+//		for _, d := range c.Diff {
+//			log.Printf("config: %s changed from %v to %v", d.Path, d.Old, d.New)
+//		}
+//	})
+//	go watcher.Watch(ctx, 10*time.Second)
+type ConfigWatcher struct {
+	OnError func(err error) // Called by Watch when a reload fails; nil ignores failures
+
+	path    string
+	format  ConfigFormat
+	opts    loadOptions
+	current atomic.Pointer[Config]
+
+	mu         sync.Mutex     // Serializes reloads
+	last       []byte         // File contents of the last reload attempt
+	pending    []notification // Changes not yet delivered to subscribers, in reload order
+	delivering bool           // A Reload call is delivering pending
+
+	subMu       sync.Mutex
+	subscribers map[int]func(ConfigChange)
+	nextSub     int
+}
+
+// notification is a change and the subscribers registered when it happened.
+type notification struct {
+	change ConfigChange
+	subs   []func(ConfigChange)
+}
+
+// ConfigChange describes a reload that changed the configuration.
+type ConfigChange struct {
+	Old  *Config
+	New  *Config
+	Diff []ConfigDiff // Changed values, sorted by path
+}
+
+// ConfigDiff is one value that differs between two configurations.
+// Old is nil for added values and New is nil for removed ones.
+type ConfigDiff struct {
+	Path string // Field path as in Config.Values, e.g. country_to_language_map.CH
+	Old  any
+	New  any
+}
+
+// NewConfigWatcher loads the file at path, as LoadConfig does with opts, and returns a watcher for it.
+// The file must load and pass Config.Validate. Call Watch or Reload to pick up changes.
+func NewConfigWatcher(path string, opts ...LoadOption) (*ConfigWatcher, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &ConfigWatcher{path: path, format: format, opts: newLoadOptions(opts), subscribers: map[int]func(ConfigChange){}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := w.decode(data)
+	if err != nil {
+		return nil, err
+	}
+	w.last = data
+	w.current.Store(cfg)
+	return w, nil
+}
+
+// Config returns the current configuration. It is compiled, so it never changes;
+// a reload replaces it with a new one.
+func (w *ConfigWatcher) Config() *Config {
+	return w.current.Load()
+}
+
+// Subscribe registers fn to be called after each reload that changes the configuration.
+// Calls are made one at a time, in the order of the reloads, without holding the reload lock,
+// so fn may call Reload or Subscribe itself; changes it causes are delivered after it returns.
+// The returned function unregisters fn.
+func (w *ConfigWatcher) Subscribe(fn func(ConfigChange)) (cancel func()) {
+	w.subMu.Lock()
+	defer w.subMu.Unlock()
+	id := w.nextSub
+	w.nextSub++
+	w.subscribers[id] = fn
+	return func() {
+		w.subMu.Lock()
+		defer w.subMu.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// Reload reads the file and, if its contents changed since the last attempt, loads and validates it.
+// A valid configuration replaces the current one and, if any value changed, subscribers are notified.
+// If another Reload is notifying subscribers, for example because a subscriber called Reload, the
+// change is queued behind its notifications and Reload returns without waiting for them.
+// On error the current configuration is kept; the same contents are not retried until the file changes.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	err := w.reload()
+	if err != nil || w.delivering || len(w.pending) == 0 {
+		w.mu.Unlock()
+		return err
+	}
+	w.delivering = true
+	delivered := false
+	defer func() {
+		if !delivered {
+			// A subscriber panicked; let the next Reload deliver the rest.
+			w.mu.Lock()
+			w.delivering = false
+			w.mu.Unlock()
+		}
+	}()
+	for len(w.pending) > 0 {
+		n := w.pending[0]
+		w.pending = w.pending[1:]
+		w.mu.Unlock()
+		for _, fn := range n.subs {
+			fn(n.change)
+		}
+		w.mu.Lock()
+	}
+	w.delivering = false
+	delivered = true
+	w.mu.Unlock()
+	return nil
+}
+
+// reload swaps in the file's configuration and queues the change for the subscribers.
+// w.mu must be held.
+func (w *ConfigWatcher) reload() error {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, w.last) {
+		return nil
+	}
+	w.last = data
+	cfg, err := w.decode(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", w.path, err)
+	}
+	old := w.current.Swap(cfg)
+	diff := DiffConfigs(old, cfg)
+	if len(diff) == 0 {
+		return nil
+	}
+
+	w.subMu.Lock()
+	subs := make([]func(ConfigChange), 0, len(w.subscribers))
+	for _, id := range slices.Sorted(maps.Keys(w.subscribers)) {
+		subs = append(subs, w.subscribers[id])
+	}
+	w.subMu.Unlock()
+	w.pending = append(w.pending, notification{ConfigChange{Old: old, New: cfg, Diff: diff}, subs})
+	return nil
+}
+
+// Watch reloads the file every interval, or DefaultConfigPollInterval if interval is not positive,
+// until ctx is done. Reload errors are passed to OnError.
+func (w *ConfigWatcher) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultConfigPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Reload(); err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+		}
+	}
+}
+
+// decode loads data with the watcher's options, validates the result and compiles it.
+func (w *ConfigWatcher) decode(data []byte) (*Config, error) {
+	cfg, err := decodeConfig(data, w.format, w.opts)
+	if err != nil {
+		return nil, err
+	}
+	if !w.opts.strict {
+		// Strict loading has validated already.
+		if err := cfg.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	cfg.compile()
+	return cfg, nil
+}
+
+// DiffConfigs returns the values that differ between old and new, sorted by path.
+// A nil config has no values.
+func DiffConfigs(old, new *Config) []ConfigDiff {
+	values := func(c *Config) map[string]any {
+		m := map[string]any{}
+		if c != nil {
+			for _, v := range c.Values() {
+				m[v.Path] = v.Value
+			}
+		}
+		return m
+	}
+	before, after := values(old), values(new)
+	paths := slices.Collect(maps.Keys(before))
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	var diff []ConfigDiff
+	for _, p := range paths {
+		if !reflect.DeepEqual(before[p], after[p]) {
+			diff = append(diff, ConfigDiff{Path: p, Old: before[p], New: after[p]})
+		}
+	}
+	return diff
+}
@@ -0,0 +1,378 @@
+package geolocation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const watchedConfig = `default_language: en
+cookie_name: site_lang
+country_to_language_map:
+  CH: [de, fr, it]
+  US: [en]
+`
+
+// rewriteConfig replaces the contents of the config file at path.
+func rewriteConfig(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewConfigWatcher(t *testing.T) {
+	w, err := NewConfigWatcher(writeConfig(t, "config.yaml", watchedConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Config().ActiveLanguages("CH"); !reflect.DeepEqual(got, []string{"de", "fr", "it"}) {
+		t.Errorf("unexpected initial config %v", got)
+	}
+
+	if _, err := NewConfigWatcher(writeConfig(t, "config.yaml", "cookie_name: site_lang\n")); err == nil {
+		t.Error("expected error for invalid initial config")
+	}
+	if _, err := NewConfigWatcher(writeConfig(t, "config.ini", "")); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+	if _, err := NewConfigWatcher("missing.yaml"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestConfigWatcher_Reload(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []ConfigChange
+	cancel := w.Subscribe(func(c ConfigChange) { changes = append(changes, c) })
+
+	if err := w.Reload(); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no change for unchanged file, got %v %v", err, changes)
+	}
+
+	before := w.Config()
+	rewriteConfig(t, path, `default_language: en
+cookie_name: site_lang
+country_to_language_map:
+  CH: [fr, de]
+  BE: [nl, fr]
+`)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected one change, got %d", len(changes))
+	}
+	want := []ConfigDiff{
+		{Path: "country_to_language_map.BE", New: []string{"nl", "fr"}},
+		{Path: "country_to_language_map.CH", Old: []string{"de", "fr", "it"}, New: []string{"fr", "de"}},
+		{Path: "country_to_language_map.US", Old: []string{"en"}},
+	}
+	if !reflect.DeepEqual(changes[0].Diff, want) {
+		t.Errorf("diff = %+v, want %+v", changes[0].Diff, want)
+	}
+	if changes[0].Old != before || changes[0].New != w.Config() {
+		t.Error("expected change to carry the old and new configs")
+	}
+	if before.ActiveLanguage("CH") != "de" {
+		t.Error("reload must not modify the previous config")
+	}
+
+	// Reformatting without changing values publishes silently.
+	rewriteConfig(t, path, "default_language: en\ncookie_name: site_lang\ncountry_to_language_map: {CH: [fr, de], BE: [nl, fr]}\n")
+	if err := w.Reload(); err != nil || len(changes) != 1 {
+		t.Errorf("expected no notification without changed values, got %v %d", err, len(changes))
+	}
+
+	cancel()
+	rewriteConfig(t, path, watchedConfig)
+	if err := w.Reload(); err != nil || len(changes) != 1 {
+		t.Errorf("expected no notification after cancel, got %v %d", err, len(changes))
+	}
+	if w.Config().ActiveLanguage("CH") != "de" {
+		t.Error("expected reload after cancel")
+	}
+}
+
+func TestConfigWatcher_InvalidReloadKeepsConfig(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	notified := false
+	w.Subscribe(func(ConfigChange) { notified = true })
+	good := w.Config()
+
+	for _, data := range []string{
+		"default_language: en\ncountry_to_language_map:\n  CH: [d_e]\n",
+		"default_language: [en\n",
+	} {
+		rewriteConfig(t, path, data)
+		err := w.Reload()
+		if err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("expected error naming the file for %q, got %v", data, err)
+		}
+		if w.Config() != good || notified {
+			t.Errorf("expected previous config to stay in effect for %q", data)
+		}
+		if err := w.Reload(); err != nil {
+			t.Errorf("expected unchanged invalid file not to be retried, got %v", err)
+		}
+	}
+
+	rewriteConfig(t, path, "default_language: fr\n")
+	if err := w.Reload(); err != nil || w.Config().DefaultLanguage != "fr" || !notified {
+		t.Errorf("expected recovery after fixing the file, got %v", err)
+	}
+}
+
+// TestConfigWatcher_ReentrantSubscriber checks that subscribers can call back into the watcher.
+func TestConfigWatcher_ReentrantSubscriber(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen []string
+	w.Subscribe(func(c ConfigChange) {
+		seen = append(seen, c.New.DefaultLanguage)
+		if c.New.DefaultLanguage == "fr" {
+			// Apply a follow-up change from within the notification.
+			rewriteConfig(t, path, "default_language: de\n")
+			if err := w.Reload(); err != nil {
+				t.Error(err)
+			}
+			w.Subscribe(func(ConfigChange) {})()
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rewriteConfig(t, path, "default_language: fr\n")
+		if err := w.Reload(); err != nil {
+			t.Error(err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload deadlocked in a re-entrant subscriber")
+	}
+	if !reflect.DeepEqual(seen, []string{"fr", "de"}) || w.Config().DefaultLanguage != "de" {
+		t.Errorf("unexpected notifications %v, config %q", seen, w.Config().DefaultLanguage)
+	}
+}
+
+// TestConfigWatcher_ConcurrentReloadOrder reloads from many goroutines and checks that subscribers
+// see the changes in the order the configs were swapped in. Run with -race.
+func TestConfigWatcher_ConcurrentReloadOrder(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	latest := w.Config()
+	w.Subscribe(func(c ConfigChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		if c.Old != latest {
+			t.Errorf("change from %q delivered after %q", c.Old.CookieName, latest.CookieName)
+		}
+		latest = c.New
+	})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 20 {
+				// Replace the file atomically so that readers never see a partial write.
+				tmp := fmt.Sprintf("%s.%d", path, i)
+				rewriteConfig(t, tmp, fmt.Sprintf("default_language: en\ncookie_name: v%d_%d\n", i, j))
+				if err := os.Rename(tmp, path); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := w.Reload(); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if latest != w.Config() {
+		t.Errorf("last delivered config %q, current %q", latest.CookieName, w.Config().CookieName)
+	}
+}
+
+// TestConfigWatcher_ReloadDuringNotification reloads while a subscriber is still handling the
+// previous change; the new change must be delivered after it.
+func TestConfigWatcher_ReloadDuringNotification(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var seen []string
+	w.Subscribe(func(c ConfigChange) {
+		if c.New.DefaultLanguage == "fr" {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		seen = append(seen, c.New.DefaultLanguage)
+		mu.Unlock()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rewriteConfig(t, path, "default_language: fr\n")
+		if err := w.Reload(); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-started
+	rewriteConfig(t, path, "default_language: de\n")
+	if err := w.Reload(); err != nil {
+		t.Error(err)
+	}
+	close(release)
+	<-done
+	if !reflect.DeepEqual(seen, []string{"fr", "de"}) {
+		t.Errorf("changes delivered as %v, want [fr de]", seen)
+	}
+}
+
+func TestConfigWatcher_Watch(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan ConfigChange, 1)
+	failed := make(chan error, 1)
+	w.Subscribe(func(c ConfigChange) { changed <- c })
+	w.OnError = func(err error) { failed <- err }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.Watch(ctx, time.Millisecond)
+	}()
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	rewriteConfig(t, path, "default_language: en\ncountry_to_language_map:\n  CH: []\n")
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected OnError for invalid file")
+	}
+	rewriteConfig(t, path, strings.Replace(watchedConfig, "[de, fr, it]", "[it]", 1))
+	select {
+	case c := <-changed:
+		if len(c.Diff) != 1 || c.Diff[0].Path != "country_to_language_map.CH" {
+			t.Errorf("unexpected diff %+v", c.Diff)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected change notification")
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	fence := &GeoFenceConfig{Response: FenceResponse{Status: 451}}
+	a := &Config{DefaultLanguage: "en", GeoFence: fence}
+	if diff := DiffConfigs(a, &Config{DefaultLanguage: "en", GeoFence: &GeoFenceConfig{Response: FenceResponse{Status: 451}}}); diff != nil {
+		t.Errorf("expected equal configs, got %+v", diff)
+	}
+	want := []ConfigDiff{
+		{Path: "cookie_name", New: "lang"},
+		{Path: "default_language", Old: "en", New: "de"},
+		{Path: "geo_fence", Old: fence},
+	}
+	if diff := DiffConfigs(a, &Config{DefaultLanguage: "de", CookieName: "lang"}); !reflect.DeepEqual(diff, want) {
+		t.Errorf("diff = %+v, want %+v", diff, want)
+	}
+	if diff := DiffConfigs(nil, a); len(diff) != 2 {
+		t.Errorf("expected every value added, got %+v", diff)
+	}
+}
+
+func TestConfigWatcher_Provider(t *testing.T) {
+	path := writeConfig(t, "config.yaml", watchedConfig)
+	w, err := NewConfigWatcher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := func() *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("CF-IPCountry", "CH")
+		r.Header.Set("Accept-Language", "en-US,en;q=0.9")
+		return r
+	}
+	var info *RequestInfo
+	handler := Middleware(WithConfig(w))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		info = RequestInfoFromContext(r.Context())
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), req())
+	if info.Config() != w.Config() || info.SiteLanguage("fr", "it") != "fr" {
+		t.Errorf("unexpected site language %q", info.SiteLanguage("fr", "it"))
+	}
+	if got := GetLanguageForCountry(req(), w, "CH", []string{"fr", "it"}); got != "fr" {
+		t.Errorf("GetLanguageForCountry = %q, want fr", got)
+	}
+
+	rewriteConfig(t, path, strings.Replace(watchedConfig, "[de, fr, it]", "[it, fr]", 1))
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	old := info
+	handler.ServeHTTP(httptest.NewRecorder(), req())
+	if info.SiteLanguage("fr", "it") != "it" || old.SiteLanguage("fr", "it") != "fr" {
+		t.Error("expected new requests to see the reloaded config and earlier ones to keep theirs")
+	}
+	if got := GetLanguageForCountry(req(), w, "CH", []string{"fr", "it"}); got != "it" {
+		t.Errorf("GetLanguageForCountry = %q after reload, want it", got)
+	}
+
+	// The config's cookie name is used as the language cookie.
+	r := req()
+	r.AddCookie(&http.Cookie{Name: "site_lang", Value: "fr"})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if info.SiteLanguage("fr", "it") != "fr" {
+		t.Errorf("expected cookie language, got %q", info.SiteLanguage("fr", "it"))
+	}
+
+	var nilCfg *Config
+	if GetLanguageForCountry(req(), nilCfg, "CH", nil) != "" {
+		t.Error("expected empty string for nil *Config")
+	}
+	if NewRequestInfo(req(), DefaultGeoInfoOptions()).SiteLanguage("en") != "" {
+		t.Error("expected empty site language without config")
+	}
+}
@@ -0,0 +1,216 @@
+package geolocation
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned by CookieSigner.
+var (
+	ErrNoCookieKey    = errors.New("geolocation: cookie signer has no key")
+	ErrInvalidCookie  = errors.New("geolocation: invalid cookie signature")
+	ErrExpiredCookie  = errors.New("geolocation: expired cookie")
+	ErrCookieTooLarge = errors.New("geolocation: cookie value too large")
+)
+
+// maxCookieLength is the longest encoded value SetSignedCookie writes; browsers cap cookies at about 4 KB.
+const maxCookieLength = 4000
+
+// CookieSigner signs, and optionally encrypts, cookie values so that clients cannot forge them.
+// Values carry an HMAC-SHA256 signature bound to the cookie name and an expiry.
+// When EncryptionKeys are set, values are also encrypted with AES-GCM and cannot be read by clients.
+//
+// To rotate keys, put the new key first and keep old keys until cookies signed with them expire.
+//
+// Example:
+//
+//	signer := geolocation.NewCookieSigner(newKey, oldKey)
+//	signer.MaxAge = 365 * 24 * time.Hour
+//	err := geolocation.SetSignedCookie(w, signer, "lang", "de", nil)
+//	lang := geolocation.GetSignedCookie(r, signer, "lang") // "" if missing, tampered or expired
+type CookieSigner struct {
+	Keys           [][]byte      // HMAC keys, newest first. The first signs; all verify.
+	EncryptionKeys [][]byte      // Optional AES-128/192/256 keys, newest first. The first encrypts; all decrypt.
+	MaxAge         time.Duration // Lifetime embedded in the value; zero means no expiry
+
+	now func() time.Time
+}
+
+// NewCookieSigner returns a CookieSigner that signs with the first key and accepts any of keys.
+func NewCookieSigner(keys ...[]byte) *CookieSigner {
+	return &CookieSigner{Keys: keys}
+}
+
+func (s *CookieSigner) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// Encode returns the signed (and, with EncryptionKeys, encrypted) form of value for the named cookie.
+// The result is safe to use as a cookie value.
+func (s *CookieSigner) Encode(name, value string) (string, error) {
+	if len(s.Keys) == 0 || len(s.Keys[0]) == 0 {
+		return "", ErrNoCookieKey
+	}
+	var expires int64
+	if s.MaxAge > 0 {
+		expires = s.clock().Add(s.MaxAge).Unix()
+	}
+	payload := []byte(strconv.FormatInt(expires, 10) + "|" + value)
+	if len(s.EncryptionKeys) > 0 {
+		gcm, err := newGCM(s.EncryptionKeys[0])
+		if err != nil {
+			return "", err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		payload = gcm.Seal(nonce, nonce, payload, []byte(name))
+	}
+	data := base64.RawURLEncoding.EncodeToString(payload)
+	return data + "." + base64.RawURLEncoding.EncodeToString(cookieMAC(s.Keys[0], name, data)), nil
+}
+
+// Decode verifies an encoded value for the named cookie and returns the original value.
+// It returns ErrInvalidCookie if the value was not produced by Encode with one of the keys,
+// and ErrExpiredCookie if its embedded expiry has passed.
+func (s *CookieSigner) Decode(name, encoded string) (string, error) {
+	data, sig, ok := strings.Cut(encoded, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	valid := false
+	for _, key := range s.Keys {
+		if len(key) > 0 && hmac.Equal(mac, cookieMAC(key, name, data)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", ErrInvalidCookie
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	if len(s.EncryptionKeys) > 0 {
+		if payload, err = s.decrypt(name, payload); err != nil {
+			return "", err
+		}
+	}
+	ts, value, ok := strings.Cut(string(payload), "|")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	expires, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	if expires != 0 && !s.clock().Before(time.Unix(expires, 0)) {
+		return "", ErrExpiredCookie
+	}
+	return value, nil
+}
+
+// decrypt opens payload with the first encryption key that authenticates it.
+func (s *CookieSigner) decrypt(name string, payload []byte) ([]byte, error) {
+	for _, key := range s.EncryptionKeys {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(payload) < gcm.NonceSize() {
+			return nil, ErrInvalidCookie
+		}
+		nonce, sealed := payload[:gcm.NonceSize()], payload[gcm.NonceSize():]
+		if plain, err := gcm.Open(nil, nonce, sealed, []byte(name)); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, ErrInvalidCookie
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// cookieMAC binds the signature to the cookie name so values cannot be moved between cookies.
+func cookieMAC(key []byte, name, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("cookie-v1\n" + name + "\n" + data))
+	return mac.Sum(nil)
+}
+
+// SetSignedCookie sets a cookie like SetCookie with its value signed (and optionally encrypted) by s.
+// When opts does not set MaxAge or Expires, the cookie lives as long as s.MaxAge.
+func SetSignedCookie(w http.ResponseWriter, s *CookieSigner, name, value string, opts *http.Cookie) error {
+	encoded, err := s.Encode(name, value)
+	if err != nil {
+		return err
+	}
+	if len(encoded) > maxCookieLength {
+		return ErrCookieTooLarge
+	}
+	if s.MaxAge > 0 && (opts == nil || opts.MaxAge == 0 && opts.Expires.IsZero()) {
+		c := http.Cookie{}
+		if opts != nil {
+			c = *opts
+		}
+		c.MaxAge = int(s.MaxAge / time.Second)
+		opts = &c
+	}
+	SetCookie(w, name, encoded, opts)
+	return nil
+}
+
+// GetSignedCookie returns the verified value of a cookie set with SetSignedCookie.
+// Missing, tampered and expired cookies all return an empty string, like a missing cookie with GetCookie.
+func GetSignedCookie(r *http.Request, s *CookieSigner, name string) string {
+	return signedCookieFromHeaders(r.Header, s, name)
+}
+
+// signedCookieFromHeaders is the framework-neutral counterpart of GetSignedCookie.
+func signedCookieFromHeaders(h Headers, s *CookieSigner, name string) string {
+	encoded := cookieFromHeaders(h, name)
+	if encoded == "" {
+		return ""
+	}
+	value, err := s.Decode(name, encoded)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// ShouldSetSignedLanguage is ShouldSetLanguage for a language cookie signed with s: it returns true
+// if the cookie is missing, tampered, expired or not a language tag. Unlike ShouldSetLanguage it
+// needs no middleware, so it also works in plain handlers and with WithContextKey.
+//
+// Example:
+//
+//	if geolocation.ShouldSetSignedLanguage(r, signer, "lang") {
+//		geolocation.SetSignedCookie(w, signer, "lang", lang, nil)
+//	}
+func ShouldSetSignedLanguage(r *http.Request, s *CookieSigner, cookieName string) bool {
+	return !IsLanguageTag(GetSignedCookie(r, s, cookieName))
+}
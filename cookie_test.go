@@ -0,0 +1,267 @@
+package geolocation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cookieRequest returns a request carrying the cookies set on w.
+func cookieRequest(w *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestCookieSigner_RoundTrip(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	signers := map[string]*CookieSigner{
+		"signed":    NewCookieSigner(key),
+		"encrypted": {Keys: [][]byte{key}, EncryptionKeys: [][]byte{[]byte("0123456789abcdef")}},
+	}
+	for name, s := range signers {
+		encoded, err := s.Encode("lang", "de-CH")
+		if err != nil {
+			t.Fatalf("%s: Encode: %v", name, err)
+		}
+		if got, err := s.Decode("lang", encoded); err != nil || got != "de-CH" {
+			t.Errorf("%s: Decode = %q, %v", name, got, err)
+		}
+		if _, err := s.Decode("other", encoded); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("%s: expected value bound to cookie name, got %v", name, err)
+		}
+	}
+
+	encrypted, _ := signers["encrypted"].Encode("lang", "de-CH")
+	if strings.Contains(encrypted, "ZGUtQ0g") { // base64 of de-CH
+		t.Errorf("expected encrypted value, got %q", encrypted)
+	}
+	again, _ := signers["encrypted"].Encode("lang", "de-CH")
+	if again == encrypted {
+		t.Error("expected a fresh nonce for each encryption")
+	}
+}
+
+func TestCookieSigner_Tampering(t *testing.T) {
+	s := NewCookieSigner([]byte("key"))
+	encoded, _ := s.Encode("lang", "en")
+	data, sig, _ := strings.Cut(encoded, ".")
+	forged, _ := NewCookieSigner([]byte("attacker")).Encode("lang", "<script>")
+
+	for _, v := range []string{
+		"",
+		"en",
+		data,
+		data + ".",
+		data + "x." + sig,
+		"MHxkZQ." + sig, // "0|de" with the signature for "en"
+		forged,
+	} {
+		if _, err := s.Decode("lang", v); !errors.Is(err, ErrInvalidCookie) {
+			t.Errorf("Decode(%q): expected ErrInvalidCookie, got %v", v, err)
+		}
+	}
+
+	if _, err := (&CookieSigner{}).Encode("lang", "en"); !errors.Is(err, ErrNoCookieKey) {
+		t.Errorf("expected ErrNoCookieKey, got %v", err)
+	}
+	bad := &CookieSigner{Keys: [][]byte{[]byte("key")}, EncryptionKeys: [][]byte{[]byte("short")}}
+	if _, err := bad.Encode("lang", "en"); err == nil {
+		t.Error("expected error for invalid AES key")
+	}
+}
+
+func TestCookieSigner_KeyRotation(t *testing.T) {
+	oldKey, newKey := []byte("old"), []byte("new")
+	oldAES, newAES := []byte("old-aes-key-0000"), []byte("new-aes-key-0000")
+	before := &CookieSigner{Keys: [][]byte{oldKey}, EncryptionKeys: [][]byte{oldAES}}
+	during := &CookieSigner{Keys: [][]byte{newKey, oldKey}, EncryptionKeys: [][]byte{newAES, oldAES}}
+	after := &CookieSigner{Keys: [][]byte{newKey}, EncryptionKeys: [][]byte{newAES}}
+
+	encoded, _ := before.Encode("lang", "fr")
+	if got, err := during.Decode("lang", encoded); err != nil || got != "fr" {
+		t.Errorf("expected old cookies to verify during rotation, got %q, %v", got, err)
+	}
+	if _, err := after.Decode("lang", encoded); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("expected retired keys to be rejected, got %v", err)
+	}
+	fresh, _ := during.Encode("lang", "fr")
+	if got, err := after.Decode("lang", fresh); err != nil || got != "fr" {
+		t.Errorf("expected new cookies to use the newest keys, got %q, %v", got, err)
+	}
+}
+
+func TestCookieSigner_Expiry(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	s := NewCookieSigner([]byte("key"))
+	s.MaxAge = time.Hour
+	s.now = func() time.Time { return now }
+
+	encoded, _ := s.Encode("lang", "bg")
+	now = now.Add(59 * time.Minute)
+	if got, err := s.Decode("lang", encoded); err != nil || got != "bg" {
+		t.Errorf("expected valid cookie before expiry, got %q, %v", got, err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := s.Decode("lang", encoded); !errors.Is(err, ErrExpiredCookie) {
+		t.Errorf("expected ErrExpiredCookie, got %v", err)
+	}
+
+	s.MaxAge = 0
+	forever, _ := s.Encode("lang", "bg")
+	now = now.Add(100 * 365 * 24 * time.Hour)
+	if _, err := s.Decode("lang", forever); err != nil {
+		t.Errorf("expected no expiry without MaxAge, got %v", err)
+	}
+}
+
+func TestSetGetSignedCookie(t *testing.T) {
+	s := NewCookieSigner([]byte("key"))
+	s.MaxAge = 24 * time.Hour
+
+	w := httptest.NewRecorder()
+	if err := SetSignedCookie(w, s, "lang", "ja", &http.Cookie{HttpOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge != 86400 || !cookies[0].HttpOnly || cookies[0].Value == "ja" {
+		t.Fatalf("unexpected cookie %+v", cookies)
+	}
+
+	r := cookieRequest(w)
+	if got := GetSignedCookie(r, s, "lang"); got != "ja" {
+		t.Errorf("GetSignedCookie = %q", got)
+	}
+	if ShouldSetSignedLanguage(r, s, "lang") {
+		t.Error("expected no need to set a valid language cookie")
+	}
+
+	tampered := httptest.NewRequest("GET", "/", nil)
+	tampered.AddCookie(&http.Cookie{Name: "lang", Value: "ja"})
+	if got := GetSignedCookie(tampered, s, "lang"); got != "" {
+		t.Errorf("expected tampered cookie to be absent, got %q", got)
+	}
+	// Without the middleware, the signer decides.
+	if !ShouldSetSignedLanguage(tampered, s, "lang") || !ShouldSetSignedLanguage(httptest.NewRequest("GET", "/", nil), s, "lang") {
+		t.Error("expected tampered or missing cookie to be replaced")
+	}
+
+	if err := SetSignedCookie(httptest.NewRecorder(), s, "big", strings.Repeat("x", 5000), nil); !errors.Is(err, ErrCookieTooLarge) {
+		t.Errorf("expected ErrCookieTooLarge, got %v", err)
+	}
+}
+
+func TestIsLanguageTag(t *testing.T) {
+	valid := []string{"de", "pt-BR", "zh-Hant-TW", "es-419", "en-US-x-twain"}
+	invalid := []string{"", "-", "de-", "1de", "de_CH", "<script>", "de CH", "en-toolongsubtag", strings.Repeat("a-", 20)}
+	for _, tag := range valid {
+		if !IsLanguageTag(tag) {
+			t.Errorf("expected %q to be valid", tag)
+		}
+	}
+	for _, tag := range invalid {
+		if IsLanguageTag(tag) {
+			t.Errorf("expected %q to be invalid", tag)
+		}
+	}
+}
+
+func TestWithLanguageCookie(t *testing.T) {
+	s := NewCookieSigner([]byte("key"))
+	var lang *LanguageInfo
+	h := Middleware(WithLanguageCookie("lang", s))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang = RequestInfoFromContext(r.Context()).LanguageInfo()
+	}))
+
+	w := httptest.NewRecorder()
+	SetSignedCookie(w, s, "lang", "fr", nil)
+	r := cookieRequest(w)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if lang.Default != "fr" || len(lang.Supported) != 3 || lang.Supported[1] != "de-DE" {
+		t.Errorf("expected cookie language first, got %+v", lang)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "fr"})
+	r.Header.Set("Accept-Language", "de-DE")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if lang.Default != "de-DE" {
+		t.Errorf("expected unsigned cookie to be ignored, got %+v", lang)
+	}
+
+	// Without a signer the cookie is read as is, but must still be a language tag.
+	var info *GeoInfo
+	h = Middleware(WithLanguageCookie("lang", nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info = GeoInfoFromContext(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if info.PreferredLanguage != "fr" {
+		t.Errorf("expected plain cookie language, got %q", info.PreferredLanguage)
+	}
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "lang", Value: "x\"onload=1"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if info.PreferredLanguage != "" {
+		t.Errorf("expected invalid cookie to be ignored, got %q", info.PreferredLanguage)
+	}
+}
+
+func TestShouldSetLanguage_Signed(t *testing.T) {
+	s := NewCookieSigner([]byte("key"))
+	var shouldSet, infoShouldSet bool
+	h := Middleware(WithLanguageCookie("lang", s))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shouldSet = ShouldSetLanguage(r, "lang")
+		infoShouldSet = RequestInfoFromContext(r.Context()).ShouldSetLanguage()
+	}))
+
+	w := httptest.NewRecorder()
+	SetSignedCookie(w, s, "lang", "ja", nil)
+	valid := cookieRequest(w)
+	tampered := httptest.NewRequest("GET", "/", nil)
+	tampered.AddCookie(&http.Cookie{Name: "lang", Value: "ja"})
+
+	tests := []struct {
+		name string
+		r    *http.Request
+		want bool
+	}{
+		{"signed", valid, false},
+		{"tampered", tampered, true},
+		{"missing", httptest.NewRequest("GET", "/", nil), true},
+	}
+	for _, tt := range tests {
+		h.ServeHTTP(httptest.NewRecorder(), tt.r)
+		if shouldSet != tt.want || infoShouldSet != tt.want {
+			t.Errorf("%s: ShouldSetLanguage = %v, RequestInfo.ShouldSetLanguage = %v, want %v", tt.name, shouldSet, infoShouldSet, tt.want)
+		}
+	}
+
+	// Without the middleware any value counts as present; ShouldSetSignedLanguage takes the signer.
+	if ShouldSetLanguage(tampered, "lang") {
+		t.Error("expected plain cookie to count as present without the middleware")
+	}
+	if !ShouldSetSignedLanguage(tampered, s, "lang") || ShouldSetSignedLanguage(valid, s, "lang") {
+		t.Error("expected ShouldSetSignedLanguage to verify the cookie without the middleware")
+	}
+	w = httptest.NewRecorder()
+	SetSignedCookie(w, s, "lang", "<script>", nil)
+	if !ShouldSetSignedLanguage(cookieRequest(w), s, "lang") {
+		t.Error("expected a signed value that is not a language tag to be replaced")
+	}
+	h = Middleware(WithContextKey("geo"), WithLanguageCookie("lang", s))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shouldSet = ShouldSetSignedLanguage(r, s, "lang")
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), tampered)
+	if !shouldSet {
+		t.Error("expected ShouldSetSignedLanguage to verify the cookie with a custom context key")
+	}
+	if NewRequestInfo(httptest.NewRequest("GET", "/", nil), DefaultGeoInfoOptions()).ShouldSetLanguage() {
+		t.Error("expected no language cookie to set without WithLanguageCookie")
+	}
+}
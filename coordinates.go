@@ -0,0 +1,215 @@
+package geolocation
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// EarthRadiusKm is the mean Earth radius used by the spherical formulas.
+const EarthRadiusKm = 6371.0088
+
+// WGS-84 ellipsoid parameters used by Vincenty.
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+// ErrNoConvergence is returned by Vincenty when the iteration does not converge,
+// which happens for nearly antipodal points.
+var ErrNoConvergence = errors.New("geolocation: vincenty formula failed to converge")
+
+// Coordinates is a point on Earth in decimal degrees.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude" yaml:"latitude"`
+	Longitude float64 `json:"longitude" yaml:"longitude"`
+}
+
+// Valid reports whether the latitude is within ±90 and the longitude within ±180.
+func (c Coordinates) Valid() bool {
+	return c.Latitude >= -90 && c.Latitude <= 90 && c.Longitude >= -180 && c.Longitude <= 180
+}
+
+// DistanceTo returns the great-circle distance to other in kilometres using the haversine formula.
+// It is accurate to about 0.5%, which is plenty for picking a nearby region.
+//
+// Example:
+//
+//	sofia := geolocation.Coordinates{Latitude: 42.6977, Longitude: 23.3219}
+//	paris := geolocation.Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+//	fmt.Printf("%.0f km\n", sofia.DistanceTo(paris)) // 1757 km
+func (c Coordinates) DistanceTo(other Coordinates) float64 {
+	lat1, lat2 := radians(c.Latitude), radians(other.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(other.Longitude - c.Longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Vincenty returns the distance to other in kilometres on the WGS-84 ellipsoid.
+// It is accurate to within millimetres but returns ErrNoConvergence for nearly antipodal points.
+func (c Coordinates) Vincenty(other Coordinates) (float64, error) {
+	l := radians(other.Longitude - c.Longitude)
+	u1 := math.Atan((1 - wgs84F) * math.Tan(radians(c.Latitude)))
+	u2 := math.Atan((1 - wgs84F) * math.Tan(radians(other.Latitude)))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+
+	lambda := l
+	for range 200 {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0, nil // coincident points
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cosSqAlpha != 0 { // both points on the equator otherwise
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		cc := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = l + (1-cc)*wgs84F*sinAlpha*(sigma+cc*sinSigma*(cos2SigmaM+cc*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) > 1e-12 {
+			continue
+		}
+		uSq := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+		a := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+		b := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+		deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		return wgs84B * a * (sigma - deltaSigma) / 1000, nil
+	}
+	return 0, ErrNoConvergence
+}
+
+// BearingTo returns the initial bearing towards other in degrees clockwise from north, in [0, 360).
+func (c Coordinates) BearingTo(other Coordinates) float64 {
+	lat1, lat2 := radians(c.Latitude), radians(other.Latitude)
+	dLon := radians(other.Longitude - c.Longitude)
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// BoundingBox is a latitude/longitude rectangle. When it crosses the antimeridian,
+// MinLongitude is greater than MaxLongitude. A box whose MinLatitude is greater than its
+// MaxLatitude is empty.
+type BoundingBox struct {
+	MinLatitude, MinLongitude float64
+	MaxLatitude, MaxLongitude float64
+}
+
+// emptyBoundingBox contains no point.
+var emptyBoundingBox = BoundingBox{MinLatitude: 90, MinLongitude: 180, MaxLatitude: -90, MaxLongitude: -180}
+
+// Empty reports whether the box contains no point.
+func (b BoundingBox) Empty() bool {
+	return b.MinLatitude > b.MaxLatitude
+}
+
+// Contains reports whether c lies inside the box.
+func (b BoundingBox) Contains(c Coordinates) bool {
+	if c.Latitude < b.MinLatitude || c.Latitude > b.MaxLatitude {
+		return false
+	}
+	if b.MinLongitude <= b.MaxLongitude {
+		return c.Longitude >= b.MinLongitude && c.Longitude <= b.MaxLongitude
+	}
+	return c.Longitude >= b.MinLongitude || c.Longitude <= b.MaxLongitude
+}
+
+// BoundingBox returns the smallest box containing every point within radiusKm of c.
+// It is useful as a cheap prefilter, e.g. in a database query, before computing exact distances.
+// Near the poles the box spans all longitudes. The box is empty if radiusKm is negative
+// or any input is not finite.
+func (c Coordinates) BoundingBox(radiusKm float64) BoundingBox {
+	if radiusKm < 0 || !isFinite(radiusKm) || !isFinite(c.Latitude) || !isFinite(c.Longitude) {
+		return emptyBoundingBox
+	}
+	d := radiusKm / EarthRadiusKm
+	lat := radians(c.Latitude)
+	minLat, maxLat := lat-d, lat+d
+	if minLat <= -math.Pi/2 || maxLat >= math.Pi/2 {
+		return BoundingBox{
+			MinLatitude:  math.Max(degrees(minLat), -90),
+			MinLongitude: -180,
+			MaxLatitude:  math.Min(degrees(maxLat), 90),
+			MaxLongitude: 180,
+		}
+	}
+	box := BoundingBox{MinLatitude: degrees(minLat), MaxLatitude: degrees(maxLat)}
+	ratio := math.Sin(d) / math.Cos(lat)
+	if d >= math.Pi/2 || ratio >= 1 {
+		box.MinLongitude, box.MaxLongitude = -180, 180
+		return box
+	}
+	dLon := degrees(math.Asin(ratio))
+	box.MinLongitude = normalizeLongitude(c.Longitude - dLon)
+	box.MaxLongitude = normalizeLongitude(c.Longitude + dLon)
+	return box
+}
+
+// Place is a named point, such as a data centre or a regional storefront.
+type Place struct {
+	Name        string `json:"name" yaml:"name"`
+	Coordinates `yaml:",inline"`
+}
+
+// RankedPlace is a Place with its distance from the point it was ranked against.
+type RankedPlace struct {
+	Place
+	DistanceKm float64
+}
+
+// Nearest ranks candidates by haversine distance from point, nearest first.
+// Places at equal distance keep their configured order.
+//
+// Example:
+//
+//	regions := []geolocation.Place{
+//		{Name: "eu-central", Coordinates: geolocation.Coordinates{Latitude: 50.11, Longitude: 8.68}},
+//		{Name: "us-east", Coordinates: geolocation.Coordinates{Latitude: 38.9, Longitude: -77.04}},
+//	}
+//	best := geolocation.Nearest(visitor, regions)[0].Name
+func Nearest(point Coordinates, candidates []Place) []RankedPlace {
+	if len(candidates) == 0 {
+		return nil
+	}
+	ranked := make([]RankedPlace, len(candidates))
+	for i, p := range candidates {
+		ranked[i] = RankedPlace{Place: p, DistanceKm: point.DistanceTo(p.Coordinates)}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].DistanceKm < ranked[j].DistanceKm
+	})
+	return ranked
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// normalizeLongitude wraps lon into [-180, 180]. Non-finite longitudes cannot be wrapped
+// and return NaN.
+func normalizeLongitude(lon float64) float64 {
+	switch {
+	case !isFinite(lon):
+		return math.NaN()
+	case lon > 180:
+		if lon = math.Mod(lon+180, 360) - 180; lon == -180 {
+			lon = 180
+		}
+	case lon < -180:
+		if lon = math.Mod(lon-180, 360) + 180; lon == 180 {
+			lon = -180
+		}
+	}
+	return lon
+}
+
+func isFinite(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
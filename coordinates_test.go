@@ -0,0 +1,246 @@
+package geolocation
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var (
+	sofia  = Coordinates{Latitude: 42.6977, Longitude: 23.3219}
+	paris  = Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+	sydney = Coordinates{Latitude: -33.8688, Longitude: 151.2093}
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestCoordinates_DistanceTo(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Coordinates
+		want float64
+	}{
+		{"same point", sofia, sofia, 0},
+		{"sofia paris", sofia, paris, 1757},
+		{"quarter meridian", Coordinates{}, Coordinates{Latitude: 90}, math.Pi / 2 * EarthRadiusKm},
+		{"across antimeridian", Coordinates{Longitude: 179.5}, Coordinates{Longitude: -179.5}, 111.2},
+	}
+	for _, tt := range tests {
+		if got := tt.a.DistanceTo(tt.b); !near(got, tt.want, 1) {
+			t.Errorf("%s: DistanceTo = %.2f, want %.2f", tt.name, got, tt.want)
+		}
+		if got := tt.b.DistanceTo(tt.a); !near(got, tt.want, 1) {
+			t.Errorf("%s: distance is not symmetric: %.2f", tt.name, got)
+		}
+	}
+}
+
+func TestCoordinates_Vincenty(t *testing.T) {
+	// Reference geodesic from Vincenty's paper (Flinders Peak to Buninyong).
+	flinders := Coordinates{Latitude: -37.95103342, Longitude: 144.42486789}
+	buninyong := Coordinates{Latitude: -37.65282114, Longitude: 143.92649554}
+	got, err := flinders.Vincenty(buninyong)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !near(got, 54.972271, 0.000001) {
+		t.Errorf("Vincenty = %.6f, want 54.972271", got)
+	}
+
+	if d, err := sofia.Vincenty(sofia); err != nil || d != 0 {
+		t.Errorf("expected 0 for coincident points, got %v, %v", d, err)
+	}
+	if d, _ := sofia.Vincenty(paris); !near(d, sofia.DistanceTo(paris), 10) {
+		t.Errorf("Vincenty and haversine disagree: %.2f vs %.2f", d, sofia.DistanceTo(paris))
+	}
+	if _, err := (Coordinates{}).Vincenty(Coordinates{Latitude: 0.5, Longitude: 179.7}); !errors.Is(err, ErrNoConvergence) {
+		t.Errorf("expected ErrNoConvergence for nearly antipodal points, got %v", err)
+	}
+}
+
+func TestCoordinates_BearingTo(t *testing.T) {
+	origin := Coordinates{}
+	tests := []struct {
+		to   Coordinates
+		want float64
+	}{
+		{Coordinates{Latitude: 10}, 0},
+		{Coordinates{Longitude: 10}, 90},
+		{Coordinates{Latitude: -10}, 180},
+		{Coordinates{Longitude: -10}, 270},
+	}
+	for _, tt := range tests {
+		if got := origin.BearingTo(tt.to); !near(got, tt.want, 1e-9) {
+			t.Errorf("BearingTo(%v) = %f, want %f", tt.to, got, tt.want)
+		}
+	}
+	if got := sofia.BearingTo(paris); !near(got, 300, 1) {
+		t.Errorf("BearingTo(paris) = %f, want about 300", got)
+	}
+}
+
+func TestCoordinates_BoundingBox(t *testing.T) {
+	box := sofia.BoundingBox(100)
+	if !box.Contains(sofia) || box.Contains(paris) {
+		t.Errorf("unexpected box around sofia: %+v", box)
+	}
+	for _, bearing := range []float64{0, 45, 90, 135, 180, 225, 270, 315} {
+		// Points 99 km away in every direction must be inside.
+		p := destination(sofia, bearing, 99)
+		if !box.Contains(p) {
+			t.Errorf("box %+v does not contain %v at bearing %v", box, p, bearing)
+		}
+	}
+
+	wrap := Coordinates{Latitude: -17, Longitude: 179.9}.BoundingBox(50)
+	if wrap.MinLongitude <= wrap.MaxLongitude {
+		t.Errorf("expected box to cross the antimeridian: %+v", wrap)
+	}
+	if !wrap.Contains(Coordinates{Latitude: -17, Longitude: -179.9}) || wrap.Contains(Coordinates{Latitude: -17, Longitude: 0}) {
+		t.Errorf("unexpected antimeridian box: %+v", wrap)
+	}
+
+	polar := Coordinates{Latitude: 89.5, Longitude: 10}.BoundingBox(100)
+	if polar.MaxLatitude != 90 || polar.MinLongitude != -180 || polar.MaxLongitude != 180 {
+		t.Errorf("expected polar box to span all longitudes: %+v", polar)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		c      Coordinates
+		radius float64
+	}{
+		{"negative radius", sofia, -100},
+		{"NaN radius", sofia, math.NaN()},
+		{"infinite radius", sofia, math.Inf(1)},
+		{"infinite longitude", Coordinates{Latitude: 10, Longitude: math.Inf(-1)}, 100},
+	} {
+		box := tt.c.BoundingBox(tt.radius)
+		if !box.Empty() {
+			t.Errorf("%s: expected empty box, got %+v", tt.name, box)
+		}
+		for _, p := range []Coordinates{sofia, paris, {}, {Latitude: 90, Longitude: 180}, {Latitude: -90, Longitude: -180}} {
+			if box.Contains(p) {
+				t.Errorf("%s: empty box contains %v", tt.name, p)
+			}
+		}
+	}
+	if box.Empty() {
+		t.Error("expected box around sofia not to be empty")
+	}
+}
+
+func TestNormalizeLongitude(t *testing.T) {
+	tests := []struct {
+		lon, want float64
+	}{
+		{0, 0},
+		{180, 180},
+		{-180, -180},
+		{190, -170},
+		{540, 180},
+		{-190, 170},
+		{-540, -180},
+		{360e9 + 10, 10},
+	}
+	for _, tt := range tests {
+		if got := normalizeLongitude(tt.lon); !near(got, tt.want, 1e-9) {
+			t.Errorf("normalizeLongitude(%v) = %v, want %v", tt.lon, got, tt.want)
+		}
+	}
+	for _, lon := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		if got := normalizeLongitude(lon); !math.IsNaN(got) {
+			t.Errorf("normalizeLongitude(%v) = %v, want NaN", lon, got)
+		}
+	}
+}
+
+// destination returns the point distanceKm away from c along bearing.
+func destination(c Coordinates, bearing, distanceKm float64) Coordinates {
+	d := distanceKm / EarthRadiusKm
+	lat1, lon1, b := radians(c.Latitude), radians(c.Longitude), radians(bearing)
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return Coordinates{Latitude: degrees(lat2), Longitude: normalizeLongitude(degrees(lon2))}
+}
+
+var testPlaces = []Place{
+	{Name: "us-east", Coordinates: Coordinates{Latitude: 38.9, Longitude: -77.04}},
+	{Name: "eu-central", Coordinates: Coordinates{Latitude: 50.11, Longitude: 8.68}},
+	{Name: "ap-southeast", Coordinates: Coordinates{Latitude: 1.35, Longitude: 103.82}},
+}
+
+func TestNearest(t *testing.T) {
+	ranked := Nearest(sofia, testPlaces)
+	want := []string{"eu-central", "us-east", "ap-southeast"}
+	if len(ranked) != len(want) {
+		t.Fatalf("expected %d places, got %d", len(want), len(ranked))
+	}
+	for i, name := range want {
+		if ranked[i].Name != name {
+			t.Errorf("rank %d: got %s, want %s", i, ranked[i].Name, name)
+		}
+		if i > 0 && ranked[i].DistanceKm < ranked[i-1].DistanceKm {
+			t.Errorf("places not sorted by distance: %+v", ranked)
+		}
+	}
+	if Nearest(sydney, testPlaces)[0].Name != "ap-southeast" {
+		t.Error("expected ap-southeast to be nearest to sydney")
+	}
+	if Nearest(sofia, nil) != nil {
+		t.Error("expected nil for no candidates")
+	}
+}
+
+func TestLocation_Coordinates(t *testing.T) {
+	if c, ok := (&Location{Country: "CH", Latitude: 47.37, Longitude: 8.54}).Coordinates(); !ok || c.Latitude != 47.37 {
+		t.Errorf("expected header coordinates, got %v, %v", c, ok)
+	}
+	if c, ok := (&Location{Country: "CH"}).Coordinates(); !ok || !near(c.Latitude, 46.8, 0.1) || !near(c.Longitude, 8.2, 0.1) {
+		t.Errorf("expected centroid of CH, got %v, %v", c, ok)
+	}
+	if _, ok := (&Location{Country: "XX"}).Coordinates(); ok {
+		t.Error("expected no coordinates for unknown country")
+	}
+}
+
+func TestCountryCentroids(t *testing.T) {
+	for _, code := range Countries() {
+		c, _ := CentroidOf(code)
+		if !c.Valid() || (c.Latitude == 0 && c.Longitude == 0) {
+			t.Errorf("country %s has invalid centroid %v", code, c)
+		}
+	}
+}
+
+func TestNearestPlaceMiddleware(t *testing.T) {
+	var got *RankedPlace
+	h := Middleware(WithNearestPlace(testPlaces))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = NearestPlaceFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "US")
+	r.Header.Set("CF-IPLatitude", "-33.87")
+	r.Header.Set("CF-IPLongitude", "151.21")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got == nil || got.Name != "ap-southeast" {
+		t.Errorf("expected coordinates to win over country, got %+v", got)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "BG")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got == nil || got.Name != "eu-central" {
+		t.Errorf("expected country centroid fallback, got %+v", got)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if got != nil {
+		t.Errorf("expected no place for unknown visitor, got %+v", got)
+	}
+}
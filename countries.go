@@ -0,0 +1,110 @@
+package geolocation
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Continent codes as reported in the CF-IPContinent header.
+const (
+	ContinentAfrica       = "AF"
+	ContinentAntarctica   = "AN"
+	ContinentAsia         = "AS"
+	ContinentEurope       = "EU"
+	ContinentNorthAmerica = "NA"
+	ContinentOceania      = "OC"
+	ContinentSouthAmerica = "SA"
+)
+
+//go:embed assets/countries.csv
+var countriesCSV []byte
+
+// CountryInfo holds reference data about a country from the built-in country registry.
+type CountryInfo struct {
+	Code      string      // ISO 3166-1 alpha-2 code, e.g. DE
+	Continent string      // Continent code, e.g. EU
+	Centroid  Coordinates // Approximate geographic centre of the country
+	TimeZone  string      // Primary IANA time zone, e.g. Europe/Berlin
+
+	Currency          string            // ISO 4217 currency code, e.g. EUR
+	FirstDayOfWeek    time.Weekday      // First day of the week on calendars
+	MeasurementSystem MeasurementSystem // Units in everyday use
+}
+
+// countries parses the embedded registry on first use.
+var countries = sync.OnceValue(func() map[string]CountryInfo {
+	records, err := csv.NewReader(bytes.NewReader(countriesCSV)).ReadAll()
+	if err != nil {
+		panic("geolocation: invalid embedded country registry: " + err.Error())
+	}
+	col := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		col[name] = i
+	}
+	m := make(map[string]CountryInfo, len(records)-1)
+	for _, rec := range records[1:] {
+		c := CountryInfo{
+			Code:      rec[col["code"]],
+			Continent: rec[col["continent"]],
+			TimeZone:  rec[col["timezone"]],
+
+			Currency:          rec[col["currency"]],
+			FirstDayOfWeek:    firstDays[rec[col["first_day"]]],
+			MeasurementSystem: MeasurementSystem(rec[col["measurement"]]),
+		}
+		c.Centroid.Latitude, _ = strconv.ParseFloat(rec[col["latitude"]], 64)
+		c.Centroid.Longitude, _ = strconv.ParseFloat(rec[col["longitude"]], 64)
+		m[c.Code] = c
+	}
+	return m
+})
+
+// LookupCountry returns the registry entry for an ISO 3166-1 alpha-2 country code (case-insensitive).
+//
+// Example:
+//
+//	if c, ok := geolocation.LookupCountry("ch"); ok {
+//		fmt.Println(c.Continent) // EU
+//	}
+func LookupCountry(code string) (CountryInfo, bool) {
+	c, ok := countries()[strings.ToUpper(code)]
+	return c, ok
+}
+
+// ContinentOf returns the continent code for a country, or empty string if the country is unknown.
+func ContinentOf(country string) string {
+	c, _ := LookupCountry(country)
+	return c.Continent
+}
+
+// CentroidOf returns the approximate centre of a country and whether the country is known.
+func CentroidOf(country string) (Coordinates, bool) {
+	c, ok := LookupCountry(country)
+	return c.Centroid, ok
+}
+
+// Countries returns the codes of all countries in the registry, sorted.
+func Countries() []string {
+	codes := make([]string, 0, len(countries()))
+	for code := range countries() {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// isContinent reports whether code is one of the continent codes.
+func isContinent(code string) bool {
+	switch code {
+	case ContinentAfrica, ContinentAntarctica, ContinentAsia, ContinentEurope,
+		ContinentNorthAmerica, ContinentOceania, ContinentSouthAmerica:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package geolocation
+
+import "testing"
+
+func TestLookupCountry(t *testing.T) {
+	cases := map[string]string{"DE": "EU", "ch": "EU", "US": "NA", "BR": "SA", "JP": "AS", "AU": "OC", "NG": "AF", "AQ": "AN"}
+	for code, want := range cases {
+		c, ok := LookupCountry(code)
+		if !ok || c.Continent != want {
+			t.Errorf("LookupCountry(%q) = %+v, %v; want continent %s", code, c, ok, want)
+		}
+		if got := ContinentOf(code); got != want {
+			t.Errorf("ContinentOf(%q) = %q, want %q", code, got, want)
+		}
+	}
+	for _, code := range []string{"", "XX", "T1", "ZZZ"} {
+		if _, ok := LookupCountry(code); ok {
+			t.Errorf("expected %q to be unknown", code)
+		}
+	}
+}
+
+func TestCountries(t *testing.T) {
+	codes := Countries()
+	if len(codes) != 250 {
+		t.Errorf("expected 250 countries, got %d", len(codes))
+	}
+	for i, code := range codes {
+		if i > 0 && codes[i-1] >= code {
+			t.Errorf("expected sorted unique codes, got %q after %q", code, codes[i-1])
+		}
+		if c, _ := LookupCountry(code); !isContinent(c.Continent) {
+			t.Errorf("country %s has invalid continent %q", code, c.Continent)
+		}
+	}
+}
+
+func TestFromHeaders_RegionContinent(t *testing.T) {
+	h := mapHeaders{"cf-ipcountry": "US", "cf-region-code": "CA"}
+	if loc := FromHeaders(h); loc.Region != "CA" || loc.Continent != "NA" {
+		t.Errorf("expected region CA and derived continent NA, got %+v", loc)
+	}
+	h["cf-ipcontinent"] = "EU"
+	if loc := FromHeaders(h); loc.Continent != "EU" {
+		t.Errorf("expected CF-IPContinent to win, got %+v", loc)
+	}
+}
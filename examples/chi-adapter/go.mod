@@ -0,0 +1,16 @@
+module chi-example
+
+go 1.25.0
+
+require (
+	github.com/go-chi/chi/v5 v5.3.2
+	go.rumenx.com/geolocation v0.0.0
+)
+
+require (
+	github.com/mssola/user_agent v0.6.0 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace go.rumenx.com/geolocation => ../..
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.rumenx.com/geolocation"
+	chiadapter "go.rumenx.com/geolocation/adapters/chi"
+)
+
+func main() {
+	fmt.Println("🌍 Starting chi Geolocation Example Server on :8084")
+	fmt.Println("📡 Try: curl http://localhost:8084/")
+	fmt.Println("🔒 Route policy: curl http://localhost:8084/checkout/42")
+	fmt.Println("🔧 Or simulate a country: curl http://localhost:8084/simulate/DE")
+	fmt.Println("🗺️  Available countries: curl http://localhost:8084/countries")
+
+	r := chi.NewRouter()
+
+	// Use geolocation middleware with route-aware options
+	r.Use(chiadapter.Middleware(
+		geolocation.WithSimulation("US"),
+		geolocation.WithSkipRoutes("/health"),
+		// Checkout requires a known country; other routes continue without one
+		geolocation.WithRoutePolicy("/checkout/{id}", geolocation.WithErrorHandler(func(err error) error {
+			return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+		})),
+	))
+
+	// Basic geolocation endpoint
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		loc := chiadapter.FromContext(r.Context())
+		if loc == nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "geolocation not available"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"location":    loc,
+			"client_info": geolocation.ParseClientInfo(r),
+			"language":    geolocation.ParseLanguageInfo(r),
+			"is_local":    geolocation.IsLocalDevelopment(r),
+		})
+	})
+
+	// Route with its own geo policy
+	r.Get("/checkout/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"order":   chi.URLParam(r, "id"),
+			"route":   chiadapter.RoutePattern(r),
+			"country": chiadapter.FromContext(r.Context()).Country,
+		})
+	})
+
+	// Simulation endpoint
+	r.Get("/simulate/{country}", func(w http.ResponseWriter, r *http.Request) {
+		country := chi.URLParam(r, "country")
+
+		// Create simulated request with custom options
+		options := &geolocation.SimulationOptions{
+			UserAgent: "chi Example Bot/1.0",
+			Languages: []string{"en", "es", "fr"},
+		}
+		simulated := geolocation.SimulateRequest(country, options)
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"simulated":   true,
+			"country":     country,
+			"location":    geolocation.FromRequest(simulated),
+			"client_info": geolocation.ParseClientInfo(simulated),
+			"language":    geolocation.ParseLanguageInfo(simulated),
+		})
+	})
+
+	// Available countries for simulation
+	r.Get("/countries", func(w http.ResponseWriter, r *http.Request) {
+		countries := geolocation.GetAvailableCountries()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"available_countries": countries,
+			"random_country":      geolocation.RandomCountry(),
+			"total_countries":     len(countries),
+		})
+	})
+
+	// Health check endpoint (skipped by the middleware)
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status":  "ok",
+			"service": "chi-geolocation-example",
+		})
+	})
+
+	fmt.Println("✅ Server started successfully!")
+	log.Fatal(http.ListenAndServe(":8084", r))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
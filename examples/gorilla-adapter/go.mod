@@ -0,0 +1,16 @@
+module gorilla-example
+
+go 1.25.0
+
+require (
+	github.com/gorilla/mux v1.8.1
+	go.rumenx.com/geolocation v0.0.0
+)
+
+require (
+	github.com/mssola/user_agent v0.6.0 // indirect
+	github.com/pelletier/go-toml/v2 v2.2.4 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace go.rumenx.com/geolocation => ../..
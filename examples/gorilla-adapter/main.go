@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.rumenx.com/geolocation"
+	gorillaadapter "go.rumenx.com/geolocation/adapters/gorilla"
+)
+
+func main() {
+	fmt.Println("🌍 Starting gorilla/mux Geolocation Example Server on :8085")
+	fmt.Println("📡 Try: curl http://localhost:8085/")
+	fmt.Println("🔒 Route policy: curl http://localhost:8085/checkout/42")
+	fmt.Println("🔧 Or simulate a country: curl http://localhost:8085/simulate/DE")
+	fmt.Println("🗺️  Available countries: curl http://localhost:8085/countries")
+
+	r := mux.NewRouter()
+
+	// Use geolocation middleware with route-aware options
+	r.Use(gorillaadapter.Middleware(
+		geolocation.WithSimulation("US"),
+		geolocation.WithSkipRoutes("/health"),
+		// Checkout requires a known country; other routes continue without one
+		geolocation.WithRoutePolicy("/checkout/{id}", geolocation.WithErrorHandler(func(err error) error {
+			return &geolocation.StatusError{Code: http.StatusForbidden, Err: err}
+		})),
+	))
+
+	// Basic geolocation endpoint
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		loc := gorillaadapter.FromContext(r.Context())
+		if loc == nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "geolocation not available"})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"location":    loc,
+			"client_info": geolocation.ParseClientInfo(r),
+			"language":    geolocation.ParseLanguageInfo(r),
+			"is_local":    geolocation.IsLocalDevelopment(r),
+		})
+	}).Methods(http.MethodGet)
+
+	// Route with its own geo policy
+	r.HandleFunc("/checkout/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"order":   mux.Vars(r)["id"],
+			"route":   gorillaadapter.RouteTemplate(r),
+			"country": gorillaadapter.FromContext(r.Context()).Country,
+		})
+	}).Methods(http.MethodGet)
+
+	// Simulation endpoint
+	r.HandleFunc("/simulate/{country}", func(w http.ResponseWriter, r *http.Request) {
+		country := mux.Vars(r)["country"]
+
+		// Create simulated request with custom options
+		options := &geolocation.SimulationOptions{
+			UserAgent: "gorilla/mux Example Bot/1.0",
+			Languages: []string{"en", "es", "fr"},
+		}
+		simulated := geolocation.SimulateRequest(country, options)
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"simulated":   true,
+			"country":     country,
+			"location":    geolocation.FromRequest(simulated),
+			"client_info": geolocation.ParseClientInfo(simulated),
+			"language":    geolocation.ParseLanguageInfo(simulated),
+		})
+	}).Methods(http.MethodGet)
+
+	// Available countries for simulation
+	r.HandleFunc("/countries", func(w http.ResponseWriter, r *http.Request) {
+		countries := geolocation.GetAvailableCountries()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"available_countries": countries,
+			"random_country":      geolocation.RandomCountry(),
+			"total_countries":     len(countries),
+		})
+	}).Methods(http.MethodGet)
+
+	// Health check endpoint (skipped by the middleware)
+	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"status":  "ok",
+			"service": "gorilla-geolocation-example",
+		})
+	}).Methods(http.MethodGet)
+
+	fmt.Println("✅ Server started successfully!")
+	log.Fatal(http.ListenAndServe(":8085", r))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
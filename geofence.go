@@ -0,0 +1,280 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// FenceMode selects how a FenceRule treats the locations it lists.
+type FenceMode string
+
+const (
+	FenceAllow FenceMode = "allow" // Only the listed locations are allowed
+	FenceDeny  FenceMode = "deny"  // The listed locations are blocked
+)
+
+// FenceRule restricts access to the paths it covers by country, region or continent.
+// A location matches the rule if it matches any of the listed countries, regions or continents.
+// An unknown location never matches, so allow rules block it and deny rules let it through.
+type FenceRule struct {
+	Paths      []string       `json:"paths,omitempty" yaml:"paths,omitempty" toml:"paths,omitempty"`                // Paths or route patterns covered ("*" suffix matches a prefix); all if empty
+	Mode       FenceMode      `json:"mode" yaml:"mode" toml:"mode"`                                                 // allow or deny
+	Countries  []string       `json:"countries,omitempty" yaml:"countries,omitempty" toml:"countries,omitempty"`    // ISO 3166-1 alpha-2 codes, e.g. CU
+	Regions    []string       `json:"regions,omitempty" yaml:"regions,omitempty" toml:"regions,omitempty"`          // ISO 3166-2 codes, e.g. US-CA
+	Continents []string       `json:"continents,omitempty" yaml:"continents,omitempty" toml:"continents,omitempty"` // Continent codes, e.g. EU
+	Response   *FenceResponse `json:"response,omitempty" yaml:"response,omitempty" toml:"response,omitempty"`       // Overrides the default response
+}
+
+// FenceResponse describes the response sent to blocked requests.
+// At most one of Redirect, HTML and JSON should be set; without any, the status text is sent.
+type FenceResponse struct {
+	Status   int    `json:"status,omitempty" yaml:"status,omitempty" toml:"status,omitempty"`       // Defaults to 403, or 302 for redirects
+	Redirect string `json:"redirect,omitempty" yaml:"redirect,omitempty" toml:"redirect,omitempty"` // URL to redirect blocked requests to
+	HTML     string `json:"html,omitempty" yaml:"html,omitempty" toml:"html,omitempty"`             // HTML body
+	JSON     any    `json:"json,omitempty" yaml:"json,omitempty" toml:"json,omitempty"`             // Value encoded as a JSON body
+}
+
+// GeoFenceConfig is the serializable form of a GeoFence, stored under geo_fence in Config.
+type GeoFenceConfig struct {
+	Rules    []FenceRule   `json:"rules" yaml:"rules" toml:"rules"`                                        // Evaluated in order
+	Response FenceResponse `json:"response,omitempty" yaml:"response,omitempty" toml:"response,omitempty"` // Default response for blocked requests
+}
+
+// FenceEvent describes a GeoFence decision, as passed to the audit callback.
+type FenceEvent struct {
+	Request  *Request   // The request being checked
+	Location *Location  // Its location
+	Allowed  bool       // Whether the request was allowed
+	Rule     *FenceRule // The rule that blocked the request, nil if allowed
+}
+
+// GeoFence is a compiled set of access rules. Every rule covering a request must allow it;
+// the first rule that blocks it decides the response. Create it with NewGeoFence or LoadGeoFence.
+type GeoFence struct {
+	Rules    []FenceRule      // Rules as configured
+	Response FenceResponse    // Default response for blocked requests
+	Audit    func(FenceEvent) // Called for every request covered by at least one rule
+
+	compiled []fenceRule
+}
+
+// hasPaths reports whether any rule of f is limited to paths. f may be nil.
+func (f *GeoFence) hasPaths() bool {
+	if f == nil {
+		return false
+	}
+	for i := range f.compiled {
+		if len(f.compiled[i].rule.Paths) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fenceRule is a FenceRule with normalized lookup sets.
+type fenceRule struct {
+	rule       *FenceRule
+	countries  map[string]bool
+	regions    map[string]bool
+	continents map[string]bool
+}
+
+// NewGeoFence validates cfg and compiles it. Codes are case-insensitive.
+//
+// Example:
+//
+//	fence, err := geolocation.NewGeoFence(geolocation.GeoFenceConfig{
+//		Rules: []geolocation.FenceRule{
+//			{Mode: geolocation.FenceDeny, Countries: []string{"CU", "IR", "KP", "SY"}},
+//			{Paths: []string{"/eu/*"}, Mode: geolocation.FenceAllow, Continents: []string{"EU"}},
+//		},
+//	})
+func NewGeoFence(cfg GeoFenceConfig) (*GeoFence, error) {
+	if err := cfg.Response.validate(); err != nil {
+		return nil, fmt.Errorf("geolocation: geo fence response: %w", err)
+	}
+	f := &GeoFence{Rules: cfg.Rules, Response: cfg.Response}
+	for i := range f.Rules {
+		c, err := compileFenceRule(&f.Rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("geolocation: geo fence rule %d: %w", i, err)
+		}
+		if r := f.Rules[i].Response; r != nil {
+			if err := r.validate(); err != nil {
+				return nil, fmt.Errorf("geolocation: geo fence rule %d response: %w", i, err)
+			}
+		}
+		f.compiled = append(f.compiled, c)
+	}
+	return f, nil
+}
+
+// compileFenceRule validates r, except for its response, and builds its lookup sets.
+func compileFenceRule(r *FenceRule) (fenceRule, error) {
+	c := fenceRule{rule: r, countries: map[string]bool{}, regions: map[string]bool{}, continents: map[string]bool{}}
+	if r.Mode != FenceAllow && r.Mode != FenceDeny {
+		return c, fmt.Errorf("mode must be %q or %q, got %q", FenceAllow, FenceDeny, r.Mode)
+	}
+	if len(r.Countries)+len(r.Regions)+len(r.Continents) == 0 {
+		return c, errors.New("no countries, regions or continents")
+	}
+	for _, code := range r.Countries {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) != 2 {
+			return c, fmt.Errorf("invalid country %q", code)
+		}
+		c.countries[code] = true
+	}
+	for _, code := range r.Regions {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if country, sub, ok := strings.Cut(code, "-"); !ok || len(country) != 2 || sub == "" {
+			return c, fmt.Errorf("invalid region %q, expected e.g. US-CA", code)
+		}
+		c.regions[code] = true
+	}
+	for _, code := range r.Continents {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !isContinent(code) {
+			return c, fmt.Errorf("invalid continent %q", code)
+		}
+		c.continents[code] = true
+	}
+	return c, nil
+}
+
+// LoadGeoFence loads the geo_fence section of a configuration file. See LoadConfig.
+func LoadGeoFence(path string) (*GeoFence, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	fence := cfg.view().GeoFence
+	if fence == nil {
+		return nil, errors.New("geolocation: config has no geo_fence section")
+	}
+	return NewGeoFence(*fence)
+}
+
+// Check applies the rules covering req to loc. It returns nil if the request is allowed,
+// or the response to send if it is blocked.
+func (f *GeoFence) Check(req *Request, loc *Location) *FenceResponse {
+	if loc == nil {
+		loc = &Location{}
+	}
+	covered := false
+	for i := range f.compiled {
+		r := &f.compiled[i]
+		if !r.covers(req) {
+			continue
+		}
+		covered = true
+		if r.matches(loc) != (r.rule.Mode == FenceAllow) {
+			if f.Audit != nil {
+				f.Audit(FenceEvent{Request: req, Location: loc, Allowed: false, Rule: r.rule})
+			}
+			if r.rule.Response != nil {
+				return r.rule.Response
+			}
+			return &f.Response
+		}
+	}
+	if covered && f.Audit != nil {
+		f.Audit(FenceEvent{Request: req, Location: loc, Allowed: true})
+	}
+	return nil
+}
+
+// Allowed reports whether a request for path from loc would be allowed. The audit callback is not called.
+func (f *GeoFence) Allowed(path string, loc *Location) bool {
+	audit := *f
+	audit.Audit = nil
+	return audit.Check(&Request{Header: http.Header{}, Path: path}, loc) == nil
+}
+
+// covers reports whether the rule applies to req.
+func (r *fenceRule) covers(req *Request) bool {
+	if len(r.rule.Paths) == 0 {
+		return true
+	}
+	return matchPath(r.rule.Paths, req.Path) || (req.Route != "" && contains(r.rule.Paths, req.Route))
+}
+
+// matches reports whether loc is one of the rule's locations.
+func (r *fenceRule) matches(loc *Location) bool {
+	country := strings.ToUpper(loc.Country)
+	if country == "" {
+		return false
+	}
+	if r.countries[country] {
+		return true
+	}
+	if continent := strings.ToUpper(loc.Continent); continent != "" && r.continents[continent] {
+		return true
+	}
+	if loc.Region != "" {
+		region := strings.ToUpper(loc.Region)
+		if !strings.Contains(region, "-") {
+			region = country + "-" + region
+		}
+		return r.regions[region]
+	}
+	return false
+}
+
+// Render returns the status code, headers and body of the response.
+// Adapters use it to write the response in their framework.
+func (r *FenceResponse) Render() (int, http.Header, []byte) {
+	header := http.Header{}
+	status := r.Status
+	switch {
+	case r.Redirect != "":
+		if status == 0 {
+			status = http.StatusFound
+		}
+		header.Set("Location", r.Redirect)
+		return status, header, nil
+	case status == 0:
+		status = http.StatusForbidden
+	}
+	switch {
+	case r.JSON != nil:
+		if body, err := json.Marshal(r.JSON); err == nil {
+			header.Set("Content-Type", "application/json")
+			return status, header, body
+		}
+	case r.HTML != "":
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		return status, header, []byte(r.HTML)
+	}
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	return status, header, []byte(http.StatusText(status))
+}
+
+// ServeHTTP writes the response.
+func (r *FenceResponse) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	status, header, body := r.Render()
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// validate checks that the response can be rendered.
+func (r *FenceResponse) validate() error {
+	if r.Status != 0 && (r.Status < 300 || r.Status > 599) {
+		return fmt.Errorf("invalid status %d", r.Status)
+	}
+	if r.Redirect != "" && r.Status != 0 && r.Status >= 400 {
+		return fmt.Errorf("redirect requires a 3xx status, got %d", r.Status)
+	}
+	if r.JSON != nil {
+		if _, err := json.Marshal(r.JSON); err != nil {
+			return fmt.Errorf("invalid json body: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func mustGeoFence(t *testing.T, cfg GeoFenceConfig) *GeoFence {
+	t.Helper()
+	f, err := NewGeoFence(cfg)
+	if err != nil {
+		t.Fatalf("NewGeoFence failed: %v", err)
+	}
+	return f
+}
+
+func TestNewGeoFence_Validation(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  GeoFenceConfig
+		want string
+	}{
+		{"bad mode", GeoFenceConfig{Rules: []FenceRule{{Mode: "block", Countries: []string{"CU"}}}}, "mode"},
+		{"empty rule", GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny}}}, "no countries"},
+		{"bad country", GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CUB"}}}}, "invalid country"},
+		{"bad region", GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Regions: []string{"CA"}}}}, "invalid region"},
+		{"bad continent", GeoFenceConfig{Rules: []FenceRule{{Mode: FenceAllow, Continents: []string{"XX"}}}}, "invalid continent"},
+		{"bad status", GeoFenceConfig{Response: FenceResponse{Status: 200}}, "invalid status"},
+		{"redirect with 403", GeoFenceConfig{Response: FenceResponse{Status: 403, Redirect: "/blocked"}}, "3xx"},
+		{"bad rule json", GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CU"}, Response: &FenceResponse{JSON: func() {}}}}}, "rule 0 response"},
+	}
+	for _, c := range cases {
+		if _, err := NewGeoFence(c.cfg); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: expected error containing %q, got %v", c.name, c.want, err)
+		}
+	}
+}
+
+func TestGeoFence_Check(t *testing.T) {
+	fence := mustGeoFence(t, GeoFenceConfig{Rules: []FenceRule{
+		{Mode: FenceDeny, Countries: []string{"cu", "KP"}, Regions: []string{"UA-43"}},
+		{Paths: []string{"/eu/*"}, Mode: FenceAllow, Continents: []string{"eu"}},
+		{Paths: []string{"/us/{state}"}, Mode: FenceAllow, Regions: []string{"US-CA", "US-NY"}},
+	}})
+
+	cases := []struct {
+		path, route string
+		loc         Location
+		allowed     bool
+	}{
+		{"/", "", Location{Country: "DE", Continent: "EU"}, true},
+		{"/", "", Location{Country: "KP", Continent: "AS"}, false},
+		{"/", "", Location{Country: "UA", Region: "43", Continent: "EU"}, false},
+		{"/", "", Location{Country: "UA", Region: "30", Continent: "EU"}, true},
+		{"/", "", Location{}, true},
+		{"/eu/prices", "", Location{Country: "FR", Continent: "EU"}, true},
+		{"/eu/prices", "", Location{Country: "US", Continent: "NA"}, false},
+		{"/eu/prices", "", Location{}, false},
+		{"/eu/prices", "", Location{Country: "CU", Continent: "EU"}, false},
+		{"/us/ca", "/us/{state}", Location{Country: "US", Region: "CA"}, true},
+		{"/us/tx", "/us/{state}", Location{Country: "US", Region: "TX"}, false},
+		{"/us/tx", "", Location{Country: "US", Region: "TX"}, true},
+	}
+	for _, c := range cases {
+		req := &Request{Header: http.Header{}, Path: c.path, Route: c.route}
+		loc := c.loc
+		if got := fence.Check(req, &loc) == nil; got != c.allowed {
+			t.Errorf("Check(%s, %+v) allowed = %v, want %v", c.path, c.loc, got, c.allowed)
+		}
+		if c.route == "" && fence.Allowed(c.path, &loc) != c.allowed {
+			t.Errorf("Allowed(%s, %+v) disagrees with Check", c.path, c.loc)
+		}
+	}
+}
+
+func TestGeoFence_AuditAndResponses(t *testing.T) {
+	ruleResponse := &FenceResponse{Redirect: "/unavailable"}
+	fence := mustGeoFence(t, GeoFenceConfig{
+		Rules: []FenceRule{
+			{Paths: []string{"/shop"}, Mode: FenceDeny, Countries: []string{"RU"}, Response: ruleResponse},
+			{Paths: []string{"/shop", "/api/*"}, Mode: FenceAllow, Countries: []string{"DE", "RU"}},
+		},
+		Response: FenceResponse{Status: http.StatusUnavailableForLegalReasons, JSON: map[string]string{"error": "unavailable"}},
+	})
+	var events []FenceEvent
+	fence.Audit = func(e FenceEvent) { events = append(events, e) }
+
+	check := func(path, country string) *FenceResponse {
+		return fence.Check(&Request{Header: http.Header{}, Path: path}, &Location{Country: country})
+	}
+	if resp := check("/shop", "RU"); resp != ruleResponse {
+		t.Errorf("expected the rule response, got %+v", resp)
+	}
+	if resp := check("/api/items", "US"); resp != &fence.Response {
+		t.Errorf("expected the default response, got %+v", resp)
+	}
+	if resp := check("/shop", "DE"); resp != nil {
+		t.Errorf("expected DE to be allowed, got %+v", resp)
+	}
+	if resp := check("/about", "US"); resp != nil {
+		t.Errorf("expected uncovered path to be allowed, got %+v", resp)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 audit events for covered requests, got %d", len(events))
+	}
+	if events[0].Allowed || events[0].Rule != &fence.Rules[0] || events[0].Location.Country != "RU" {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Allowed || events[1].Rule != &fence.Rules[1] || events[1].Request.Path != "/api/items" {
+		t.Errorf("unexpected second event: %+v", events[1])
+	}
+	if !events[2].Allowed || events[2].Rule != nil {
+		t.Errorf("unexpected third event: %+v", events[2])
+	}
+}
+
+func TestFenceResponse_Render(t *testing.T) {
+	cases := []struct {
+		resp        FenceResponse
+		status      int
+		contentType string
+		location    string
+		body        string
+	}{
+		{FenceResponse{}, 403, "text/plain; charset=utf-8", "", "Forbidden"},
+		{FenceResponse{Status: 451}, 451, "text/plain; charset=utf-8", "", "Unavailable For Legal Reasons"},
+		{FenceResponse{Redirect: "https://example.com/region"}, 302, "", "https://example.com/region", ""},
+		{FenceResponse{Redirect: "/x", Status: 307}, 307, "", "/x", ""},
+		{FenceResponse{HTML: "<h1>Not available</h1>"}, 403, "text/html; charset=utf-8", "", "<h1>Not available</h1>"},
+		{FenceResponse{JSON: map[string]any{"error": "blocked"}}, 403, "application/json", "", `{"error":"blocked"}`},
+	}
+	for _, c := range cases {
+		status, header, body := c.resp.Render()
+		if status != c.status || header.Get("Content-Type") != c.contentType || header.Get("Location") != c.location || string(body) != c.body {
+			t.Errorf("Render(%+v) = %d %v %q", c.resp, status, header, body)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	(&FenceResponse{Status: 451, HTML: "<p>no</p>"}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != 451 || rec.Body.String() != "<p>no</p>" || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("unexpected response: %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestLoadGeoFence(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"fence.yaml": `default_language: en
+geo_fence:
+  response:
+    status: 451
+    json:
+      error: unavailable in your region
+  rules:
+    - mode: deny
+      countries: [CU, IR, KP, SY]
+    - paths: ["/eu/*"]
+      mode: allow
+      continents: [EU]
+      response:
+        redirect: /not-in-eu
+`,
+		"fence.json": `{"default_language":"en","geo_fence":{"response":{"status":451,"json":{"error":"unavailable in your region"}},
+"rules":[{"mode":"deny","countries":["CU","IR","KP","SY"]},{"paths":["/eu/*"],"mode":"allow","continents":["EU"],"response":{"redirect":"/not-in-eu"}}]}}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(data), 0o600)
+		fence, err := LoadGeoFence(path)
+		if err != nil {
+			t.Fatalf("%s: LoadGeoFence failed: %v", name, err)
+		}
+		if len(fence.Rules) != 2 || fence.Rules[1].Response == nil || fence.Rules[1].Response.Redirect != "/not-in-eu" {
+			t.Errorf("%s: unexpected rules: %+v", name, fence.Rules)
+		}
+		status, _, body := fence.Check(&Request{Header: http.Header{}, Path: "/"}, &Location{Country: "IR"}).Render()
+		var payload map[string]string
+		if status != 451 || json.Unmarshal(body, &payload) != nil || payload["error"] != "unavailable in your region" {
+			t.Errorf("%s: unexpected default response %d %s", name, status, body)
+		}
+		if fence.Allowed("/eu/x", &Location{Country: "US", Continent: "NA"}) {
+			t.Errorf("%s: expected /eu/ to be restricted to Europe", name)
+		}
+	}
+
+	path := filepath.Join(dir, "plain.json")
+	os.WriteFile(path, []byte(`{"default_language":"en"}`), 0o600)
+	if _, err := LoadGeoFence(path); err == nil {
+		t.Error("expected error for config without geo_fence")
+	}
+}
+
+func TestGeoFenceMiddleware(t *testing.T) {
+	fence := mustGeoFence(t, GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"KP"}}}})
+	var called bool
+	h := GeoFenceMiddleware(fence, WithSkipPaths("/health"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.URL.Path != "/health" && FromContext(r.Context()) == nil {
+			t.Error("expected location in context for allowed requests")
+		}
+	}))
+
+	cases := []struct {
+		path, country string
+		status        int
+		called        bool
+	}{
+		{"/", "DE", http.StatusOK, true},
+		{"/", "KP", http.StatusForbidden, false},
+		{"/health", "KP", http.StatusOK, true},
+	}
+	for _, c := range cases {
+		called = false
+		r := httptest.NewRequest("GET", c.path, nil)
+		r.Header.Set("CF-IPCountry", c.country)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != c.status || called != c.called {
+			t.Errorf("%s from %s: got status %d (called %v), want %d (called %v)", c.path, c.country, rec.Code, called, c.status, c.called)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package geolocation
+
+import (
+	"errors"
+	"strings"
+)
+
+// MaxGeohashPrecision is the longest geohash produced, about 3.7 cm × 1.9 cm.
+const MaxGeohashPrecision = 12
+
+// ErrInvalidGeohash is returned when a geohash is empty, too long or contains characters
+// outside the geohash alphabet.
+var ErrInvalidGeohash = errors.New("geolocation: invalid geohash")
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// PrivacyLevel is the geohash precision used when reducing a location to a coarse cell.
+// Lower levels give larger cells and reveal less about the visitor.
+type PrivacyLevel int
+
+// Privacy levels with the approximate cell size at the equator.
+const (
+	PrivacyRegion       PrivacyLevel = 3 // ~156 km × 156 km
+	PrivacyCity         PrivacyLevel = 4 // ~39 km × 20 km
+	PrivacyDistrict     PrivacyLevel = 5 // ~4.9 km × 4.9 km
+	PrivacyNeighborhood PrivacyLevel = 6 // ~1.2 km × 0.6 km
+)
+
+// EncodeGeohash returns the geohash of c with the given number of characters.
+// precision is clamped to [1, MaxGeohashPrecision]. Coordinates that are not Valid,
+// including NaN, have no cell and return an empty string.
+//
+// Example:
+//
+//	hash := geolocation.EncodeGeohash(geolocation.Coordinates{Latitude: 42.6977, Longitude: 23.3219}, 6) // sx8dfs
+func EncodeGeohash(c Coordinates, precision int) string {
+	if !c.Valid() {
+		return ""
+	}
+	precision = min(max(precision, 1), MaxGeohashPrecision)
+	lat := [2]float64{-90, 90}
+	lon := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	bits, ch, even := 0, 0, true
+	for len(hash) < precision {
+		r, v := &lat, c.Latitude
+		if even {
+			r, v = &lon, c.Longitude
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// DecodeGeohash returns the cell described by hash. Decoding is case-insensitive.
+// Use BoundingBox.Center for a single point.
+func DecodeGeohash(hash string) (BoundingBox, error) {
+	if hash == "" || len(hash) > MaxGeohashPrecision {
+		return BoundingBox{}, ErrInvalidGeohash
+	}
+	lat := [2]float64{-90, 90}
+	lon := [2]float64{-180, 180}
+	even := true
+	for _, r := range strings.ToLower(hash) {
+		ch := strings.IndexRune(geohashAlphabet, r)
+		if ch < 0 {
+			return BoundingBox{}, ErrInvalidGeohash
+		}
+		for bit := 4; bit >= 0; bit-- {
+			rng := &lat
+			if even {
+				rng = &lon
+			}
+			mid := (rng[0] + rng[1]) / 2
+			if ch&(1<<bit) != 0 {
+				rng[0] = mid
+			} else {
+				rng[1] = mid
+			}
+			even = !even
+		}
+	}
+	return BoundingBox{MinLatitude: lat[0], MinLongitude: lon[0], MaxLatitude: lat[1], MaxLongitude: lon[1]}, nil
+}
+
+// GeohashNeighbors returns the cells adjacent to hash, in the order N, NE, E, SE, S, SW, W, NW.
+// Neighbors wrap across the antimeridian; those beyond a pole are empty strings.
+func GeohashNeighbors(hash string) ([8]string, error) {
+	var neighbors [8]string
+	box, err := DecodeGeohash(hash)
+	if err != nil {
+		return neighbors, err
+	}
+	center := box.Center()
+	height := box.MaxLatitude - box.MinLatitude
+	width := box.MaxLongitude - box.MinLongitude
+	offsets := [8][2]float64{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	for i, d := range offsets {
+		lat := center.Latitude + d[0]*height
+		if lat > 90 || lat < -90 {
+			continue
+		}
+		lon := normalizeLongitude(center.Longitude + d[1]*width)
+		neighbors[i] = EncodeGeohash(Coordinates{Latitude: lat, Longitude: lon}, len(hash))
+	}
+	return neighbors, nil
+}
+
+// Center returns the midpoint of the box, taking a box that crosses the antimeridian into account.
+func (b BoundingBox) Center() Coordinates {
+	maxLon := b.MaxLongitude
+	if b.MinLongitude > maxLon {
+		maxLon += 360
+	}
+	return Coordinates{
+		Latitude:  (b.MinLatitude + b.MaxLatitude) / 2,
+		Longitude: normalizeLongitude((b.MinLongitude + maxLon) / 2),
+	}
+}
+
+// Cell returns the geohash cell containing the location at the given privacy level,
+// suitable for storing in analytics instead of coordinates. Returns an empty string
+// if the location has no coordinates or they are out of range.
+//
+// Example:
+//
+//	cell := geolocation.FromRequest(r).Cell(geolocation.PrivacyCity) // e.g. sx8d
+func (l *Location) Cell(level PrivacyLevel) string {
+	if !l.HasCoordinates() {
+		return ""
+	}
+	return EncodeGeohash(Coordinates{Latitude: l.Latitude, Longitude: l.Longitude}, int(level))
+}
@@ -0,0 +1,140 @@
+package geolocation
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEncodeGeohash(t *testing.T) {
+	tests := []struct {
+		c         Coordinates
+		precision int
+		want      string
+	}{
+		{Coordinates{Latitude: 57.64911, Longitude: 10.40744}, 11, "u4pruydqqvj"},
+		{Coordinates{Latitude: 42.6977, Longitude: 23.3219}, 6, "sx8dfs"},
+		{Coordinates{Latitude: 42.6977, Longitude: 23.3219}, 0, "s"},
+		{Coordinates{Latitude: 90, Longitude: 180}, 4, "zzzz"},
+		{Coordinates{Latitude: -90, Longitude: -180}, 4, "0000"},
+	}
+	for _, tt := range tests {
+		if got := EncodeGeohash(tt.c, tt.precision); got != tt.want {
+			t.Errorf("EncodeGeohash(%v, %d) = %q, want %q", tt.c, tt.precision, got, tt.want)
+		}
+	}
+	if got := EncodeGeohash(sofia, 20); len(got) != MaxGeohashPrecision {
+		t.Errorf("expected precision to be clamped, got %q", got)
+	}
+
+	for _, c := range []Coordinates{
+		{Latitude: 90.5, Longitude: 0},
+		{Latitude: -91, Longitude: 0},
+		{Latitude: 0, Longitude: 180.1},
+		{Latitude: 0, Longitude: -360},
+		{Latitude: math.NaN(), Longitude: 0},
+		{Latitude: 0, Longitude: math.NaN()},
+		{Latitude: math.Inf(1), Longitude: math.Inf(-1)},
+	} {
+		if got := EncodeGeohash(c, 6); got != "" {
+			t.Errorf("EncodeGeohash(%v) = %q, want empty for invalid coordinates", c, got)
+		}
+	}
+}
+
+func TestDecodeGeohash_RoundTrip(t *testing.T) {
+	points := []Coordinates{
+		sofia,
+		sydney,
+		{Latitude: 90, Longitude: 0},
+		{Latitude: -90, Longitude: 0},
+		{Latitude: 89.9999, Longitude: -135},
+		{Latitude: -89.9999, Longitude: 45},
+		{Latitude: 0, Longitude: 180},
+		{Latitude: 0, Longitude: -180},
+		{Latitude: -16.5, Longitude: 179.9999},
+		{Latitude: -16.5, Longitude: -179.9999},
+	}
+	for _, p := range points {
+		for precision := 1; precision <= MaxGeohashPrecision; precision++ {
+			hash := EncodeGeohash(p, precision)
+			box, err := DecodeGeohash(hash)
+			if err != nil {
+				t.Fatalf("DecodeGeohash(%q): %v", hash, err)
+			}
+			if !box.Contains(p) {
+				t.Errorf("cell %q %+v does not contain %v", hash, box, p)
+			}
+			if again := EncodeGeohash(box.Center(), precision); again != hash {
+				t.Errorf("center of %q encodes to %q", hash, again)
+			}
+		}
+	}
+}
+
+func TestDecodeGeohash_Invalid(t *testing.T) {
+	for _, hash := range []string{"", "abc", "u4pruydqqvjxx", "sx8d!"} {
+		if _, err := DecodeGeohash(hash); !errors.Is(err, ErrInvalidGeohash) {
+			t.Errorf("DecodeGeohash(%q): expected ErrInvalidGeohash, got %v", hash, err)
+		}
+	}
+	if _, err := DecodeGeohash("SX8DFS"); err != nil {
+		t.Errorf("expected decoding to be case-insensitive, got %v", err)
+	}
+}
+
+func TestGeohashNeighbors(t *testing.T) {
+	got, err := GeohashNeighbors("u4pruyd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [8]string{"u4pruyf", "u4pruyg", "u4pruye", "u4pruy7", "u4pruy6", "u4pruy3", "u4pruy9", "u4pruyc"}
+	if got != want {
+		t.Errorf("GeohashNeighbors = %v, want %v", got, want)
+	}
+
+	// A cell on the eastern edge wraps to the western edge.
+	east := EncodeGeohash(Coordinates{Latitude: -16.5, Longitude: 179.99}, 5)
+	n, _ := GeohashNeighbors(east)
+	west := EncodeGeohash(Coordinates{Latitude: -16.5, Longitude: -179.99}, 5)
+	if n[2] != west {
+		t.Errorf("east neighbor of %q = %q, want %q", east, n[2], west)
+	}
+	if back, _ := GeohashNeighbors(west); back[6] != east {
+		t.Errorf("west neighbor of %q = %q, want %q", west, back[6], east)
+	}
+
+	// Nothing lies north of the north pole.
+	pole, _ := GeohashNeighbors(EncodeGeohash(Coordinates{Latitude: 90, Longitude: 0}, 4))
+	if pole[0] != "" || pole[1] != "" || pole[7] != "" || pole[4] == "" {
+		t.Errorf("unexpected neighbors at the north pole: %v", pole)
+	}
+
+	if _, err := GeohashNeighbors("!"); !errors.Is(err, ErrInvalidGeohash) {
+		t.Errorf("expected ErrInvalidGeohash, got %v", err)
+	}
+}
+
+func TestLocation_Cell(t *testing.T) {
+	loc := &Location{Country: "BG", Latitude: 42.6977, Longitude: 23.3219}
+	tests := []struct {
+		level PrivacyLevel
+		want  string
+	}{
+		{PrivacyRegion, "sx8"},
+		{PrivacyCity, "sx8d"},
+		{PrivacyDistrict, "sx8df"},
+		{PrivacyNeighborhood, "sx8dfs"},
+	}
+	for _, tt := range tests {
+		if got := loc.Cell(tt.level); got != tt.want {
+			t.Errorf("Cell(%d) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+	if got := (&Location{Country: "BG"}).Cell(PrivacyCity); got != "" {
+		t.Errorf("expected no cell without coordinates, got %q", got)
+	}
+	if got := (&Location{Country: "BG", Latitude: 142.7, Longitude: 23.3}).Cell(PrivacyCity); got != "" {
+		t.Errorf("expected no cell for out-of-range coordinates, got %q", got)
+	}
+}
@@ -3,29 +3,50 @@
 package geolocation
 
 import (
-	"encoding/json"
-	"errors"
 	"net/http"
-	"os"
-	"strconv"
+	"slices"
 	"strings"
-
-	"github.com/mssola/user_agent"
-	"gopkg.in/yaml.v3"
 )
 
 // Location represents a geolocation result, typically extracted from Cloudflare headers.
 type Location struct {
-	IP      string // The user's public IP address (from CF-Connecting-IP)
-	Country string // The user's country code (from CF-IPCountry)
+	IP        string  // The user's public IP address (from CF-Connecting-IP)
+	Country   string  // The user's country code (from CF-IPCountry)
+	Region    string  // The user's region (ISO 3166-2 subdivision without the country, from CF-Region-Code)
+	Continent string  // The user's continent code (from CF-IPContinent, or derived from the country)
+	Latitude  float64 // The user's approximate latitude (from CF-IPLatitude)
+	Longitude float64 // The user's approximate longitude (from CF-IPLongitude)
+	TimeZone  string  // The user's IANA time zone (from CF-Timezone, or the country's primary zone)
+}
+
+// HasCoordinates reports whether the location carries coordinates.
+// 0,0 is treated as unknown, as it is what missing headers produce.
+func (l *Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// Coordinates returns the location's coordinates. If the location has none, it falls back to
+// the centroid of its country; ok is false when neither is known.
+func (l *Location) Coordinates() (c Coordinates, ok bool) {
+	if l.HasCoordinates() {
+		return Coordinates{Latitude: l.Latitude, Longitude: l.Longitude}, true
+	}
+	return CentroidOf(l.Country)
 }
 
 // ClientInfo holds browser, OS, and device information parsed from the User-Agent header.
 type ClientInfo struct {
 	BrowserName    string // e.g., Chrome, Firefox
 	BrowserVersion string // e.g., 123.0.0.0
-	OS             string // e.g., Windows NT 10.0
+	OS             string // e.g., Windows NT 10.0 (raw, kept for compatibility)
+	OSName         string // e.g., Windows, macOS, iOS, Android
+	OSVersion      string // e.g., 10, 10.15.7, 17.2
+	Engine         string // e.g., Blink, Gecko, WebKit
+	EngineVersion  string // e.g., 123.0.0.0
 	Device         string // e.g., Mobile, Desktop, Tablet
+	InAppBrowser   bool   // true for in-app browsers (Facebook, Instagram, TikTok, LINE, ...)
+	InAppName      string // e.g., Facebook, Instagram, TikTok, LINE
+	WebView        bool   // true for embedded WebViews (Android "; wv)", iOS WKWebView)
 }
 
 // LanguageInfo holds the user's preferred and supported languages from Accept-Language.
@@ -34,30 +55,71 @@ type LanguageInfo struct {
 	Supported []string // All languages in order of preference
 }
 
-// Resolution holds screen resolution information.
+// Resolution holds screen resolution and viewport information.
 type Resolution struct {
-	Width  int // Screen width in pixels
-	Height int // Screen height in pixels
+	Width          int     // Screen width in pixels
+	Height         int     // Screen height in pixels
+	DPR            float64 // Device pixel ratio, e.g. 2 for Retina displays
+	ViewportWidth  int     // Viewport (layout) width in CSS pixels
+	ViewportHeight int     // Viewport (layout) height in CSS pixels
+	Orientation    string  // "landscape", "portrait", or empty if unknown
 }
 
 // GeoInfo holds all geolocation and client information.
 type GeoInfo struct {
-	CountryCode       string     `json:"country_code"`
-	IP                string     `json:"ip"`
-	PreferredLanguage string     `json:"preferred_language"`
-	AllLanguages      []string   `json:"all_languages"`
-	OS                string     `json:"os"`
-	Browser           string     `json:"browser"`
-	BrowserVersion    string     `json:"browser_version"`
-	Device            string     `json:"device"`
-	Resolution        Resolution `json:"resolution"`
+	CountryCode       string      `json:"country_code"`
+	Region            string      `json:"region,omitempty"`
+	Continent         string      `json:"continent,omitempty"`
+	Latitude          float64     `json:"latitude,omitempty"`
+	Longitude         float64     `json:"longitude,omitempty"`
+	TimeZone          string      `json:"time_zone,omitempty"`
+	IP                string      `json:"ip"`
+	PreferredLanguage string      `json:"preferred_language"`
+	AllLanguages      []string    `json:"all_languages"`
+	OS                string      `json:"os"`
+	OSName            string      `json:"os_name"`
+	OSVersion         string      `json:"os_version"`
+	Browser           string      `json:"browser"`
+	BrowserVersion    string      `json:"browser_version"`
+	Engine            string      `json:"engine"`
+	EngineVersion     string      `json:"engine_version"`
+	Device            string      `json:"device"`
+	InAppBrowser      bool        `json:"in_app_browser"`
+	InAppName         string      `json:"in_app_name,omitempty"`
+	WebView           bool        `json:"webview"`
+	Resolution        Resolution  `json:"resolution"`
+	Compliance        *Compliance `json:"compliance,omitempty"`
 }
 
-// Config holds module configuration, including country-to-language mapping, defaults, and cookie name.
+// Config holds module configuration, including country-to-language mapping, defaults, cookie name
+// and optional geo fence rules.
+//
+// Configs created by NewConfig, ConfigBuilder and ConfigWatcher are compiled: every method and every
+// consumer, such as ActiveLanguages, Values, Validate, WithConfig and NewLanguageSwitcher, reads
+// copies made at construction and never modified, so a compiled Config is safe for concurrent use
+// and later changes to its fields have no effect. To change a compiled Config, build a new one
+// with Builder. Configs returned by LoadConfig and the other loaders are not compiled; their fields
+// may be adjusted before passing them to NewConfig or sharing them.
 type Config struct {
-	DefaultLanguage      string              `json:"default_language" yaml:"default_language"`
-	CountryToLanguageMap map[string][]string `json:"country_to_language_map" yaml:"country_to_language_map"`
-	CookieName           string              `json:"cookie_name" yaml:"cookie_name"`
+	DefaultLanguage      string              `json:"default_language" yaml:"default_language" toml:"default_language"`
+	CountryToLanguageMap map[string][]string `json:"country_to_language_map" yaml:"country_to_language_map" toml:"country_to_language_map"`
+	CookieName           string              `json:"cookie_name" yaml:"cookie_name" toml:"cookie_name"`
+	GeoFence             *GeoFenceConfig     `json:"geo_fence,omitempty" yaml:"geo_fence,omitempty" toml:"geo_fence,omitempty"`
+
+	positions map[string]position // Source positions of YAML values by field path, see Validate
+	sources   map[string]string   // Layers that set each value, see MergeConfigs
+	compiled  *compiledConfig     // Lookups of compiled configs; nil for struct literals
+}
+
+// ConfigProvider supplies the current configuration. A *Config provides itself; a ConfigWatcher
+// provides the most recently loaded file, so code reading the config through a provider follows reloads.
+type ConfigProvider interface {
+	Config() *Config
+}
+
+// Config returns c, so a static *Config can be used wherever a ConfigProvider is expected.
+func (c *Config) Config() *Config {
+	return c
 }
 
 // FromRequest extracts geolocation info from Cloudflare headers in the request.
@@ -67,35 +129,18 @@ type Config struct {
 //	loc := geolocation.FromRequest(r)
 //	fmt.Println(loc.IP, loc.Country)
 func FromRequest(r *http.Request) *Location {
-	return &Location{
-		IP:      r.Header.Get("CF-Connecting-IP"),
-		Country: r.Header.Get("CF-IPCountry"),
-	}
+	return FromHeaders(r.Header)
 }
 
 // ParseClientInfo parses the User-Agent header for browser, OS, and device info.
+// Results are served from the User-Agent cache when it is enabled (see EnableUACache).
 //
 // Example:
 //
 //	info := geolocation.ParseClientInfo(r)
-//	fmt.Println(info.BrowserName, info.OS, info.Device)
+//	fmt.Println(info.BrowserName, info.OSName, info.OSVersion, info.Device)
 func ParseClientInfo(r *http.Request) *ClientInfo {
-	ua := user_agent.New(r.UserAgent())
-	name, version := ua.Browser()
-	device := "Desktop"
-	userAgent := r.UserAgent()
-	if ua.Mobile() {
-		device = "Mobile"
-	} else if strings.Contains(strings.ToLower(userAgent), "ipad") ||
-		strings.Contains(strings.ToLower(userAgent), "tablet") {
-		device = "Tablet"
-	}
-	return &ClientInfo{
-		BrowserName:    name,
-		BrowserVersion: version,
-		OS:             ua.OS(),
-		Device:         device,
-	}
+	return ClientInfoFromHeaders(r.Header)
 }
 
 // ParseLanguageInfo parses the Accept-Language header for language preferences.
@@ -105,19 +150,7 @@ func ParseClientInfo(r *http.Request) *ClientInfo {
 //	lang := geolocation.ParseLanguageInfo(r)
 //	fmt.Println(lang.Default, lang.Supported)
 func ParseLanguageInfo(r *http.Request) *LanguageInfo {
-	header := r.Header.Get("Accept-Language")
-	if header == "" {
-		return &LanguageInfo{}
-	}
-	langs := parseAcceptLanguage(header)
-	defaultLang := ""
-	if len(langs) > 0 {
-		defaultLang = langs[0]
-	}
-	return &LanguageInfo{
-		Default:   defaultLang,
-		Supported: langs,
-	}
+	return LanguageInfoFromHeaders(r.Header)
 }
 
 // parseAcceptLanguage parses the Accept-Language header into a slice of language codes.
@@ -137,43 +170,21 @@ func LookupIP(ip string) (*Location, error) {
 	return &Location{IP: ip}, nil
 }
 
-// LoadConfig loads configuration from a JSON or YAML file.
-// The format is determined by the file extension (.json or .yaml/.yml).
-func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	cfg := &Config{}
-	switch {
-	case strings.HasSuffix(path, ".json"):
-		err = json.Unmarshal(data, cfg)
-	case strings.HasSuffix(path, ".yaml"), strings.HasSuffix(path, ".yml"):
-		err = yaml.Unmarshal(data, cfg)
-	default:
-		return nil, errors.New("unsupported config file format")
-	}
-	if err != nil {
-		return nil, err
-	}
-	return cfg, nil
-}
-
 // ActiveLanguages returns the list of languages for a given country code, or the default if not mapped.
+// The result is a copy that the caller may modify. Country codes match case-insensitively.
 func (c *Config) ActiveLanguages(country string) []string {
-	if langs, ok := c.CountryToLanguageMap[country]; ok && len(langs) > 0 {
-		return langs
+	if langs, ok := c.countryLanguages(country); ok && len(langs) > 0 {
+		return slices.Clone(langs)
 	}
-	return []string{c.DefaultLanguage}
+	return []string{c.defaultLanguage()}
 }
 
 // ActiveLanguage returns the first language for a given country code, or the default if not mapped.
 func (c *Config) ActiveLanguage(country string) string {
-	langs := c.ActiveLanguages(country)
-	if len(langs) > 0 {
+	if langs, ok := c.countryLanguages(country); ok && len(langs) > 0 {
 		return langs[0]
 	}
-	return c.DefaultLanguage
+	return c.defaultLanguage()
 }
 
 // GetCookie retrieves a named cookie value from the request. Returns empty string if not found.
@@ -207,23 +218,6 @@ func SetCookie(w http.ResponseWriter, name, value string, opts *http.Cookie) {
 	http.SetCookie(w, cookie)
 }
 
-// GetResolution retrieves screen resolution from custom headers (if set by frontend JS).
-func GetResolution(r *http.Request) Resolution {
-	width := 0
-	height := 0
-	if w := r.Header.Get("X-Screen-Width"); w != "" {
-		if parsed, err := strconv.Atoi(w); err == nil {
-			width = parsed
-		}
-	}
-	if h := r.Header.Get("X-Screen-Height"); h != "" {
-		if parsed, err := strconv.Atoi(h); err == nil {
-			height = parsed
-		}
-	}
-	return Resolution{Width: width, Height: height}
-}
-
 // GetGeoInfo returns all geolocation and client information in a single struct.
 //
 // Example:
@@ -231,41 +225,61 @@ func GetResolution(r *http.Request) Resolution {
 //	info := geolocation.GetGeoInfo(r)
 //	fmt.Printf("Country: %s, Browser: %s, Device: %s", info.CountryCode, info.Browser, info.Device)
 func GetGeoInfo(r *http.Request) *GeoInfo {
-	loc := FromRequest(r)
-	client := ParseClientInfo(r)
-	lang := ParseLanguageInfo(r)
-	resolution := GetResolution(r)
+	return GeoInfoFromHeaders(r.Header)
+}
 
+// newGeoInfo assembles a GeoInfo from its parts.
+func newGeoInfo(loc *Location, client *ClientInfo, lang *LanguageInfo, resolution Resolution) *GeoInfo {
 	return &GeoInfo{
 		CountryCode:       loc.Country,
+		Region:            loc.Region,
+		Continent:         loc.Continent,
+		Latitude:          loc.Latitude,
+		Longitude:         loc.Longitude,
+		TimeZone:          loc.TimeZone,
 		IP:                loc.IP,
 		PreferredLanguage: lang.Default,
 		AllLanguages:      lang.Supported,
 		OS:                client.OS,
+		OSName:            client.OSName,
+		OSVersion:         client.OSVersion,
 		Browser:           client.BrowserName,
 		BrowserVersion:    client.BrowserVersion,
+		Engine:            client.Engine,
+		EngineVersion:     client.EngineVersion,
 		Device:            client.Device,
+		InAppBrowser:      client.InAppBrowser,
+		InAppName:         client.InAppName,
+		WebView:           client.WebView,
 		Resolution:        resolution,
 	}
 }
 
 // GetLanguageForCountry returns the best language for a given country code,
 // considering browser languages and available site languages.
+// cfg may be a *Config or a ConfigWatcher, whose current config is used.
 //
 // Logic:
 // 1. If browser preferred language matches a country language and is available, use it
 // 2. Check all browser languages for a match with available languages
 // 3. Use the first country language as fallback
 // 4. Returns empty string if no match found
-func GetLanguageForCountry(r *http.Request, cfg *Config, countryCode string, availableSiteLanguages []string) string {
+func GetLanguageForCountry(r *http.Request, cfg ConfigProvider, countryCode string, availableSiteLanguages []string) string {
+	if cfg == nil {
+		return ""
+	}
+	return languageForCountry(cfg.Config(), ParseLanguageInfo(r), countryCode, availableSiteLanguages)
+}
+
+// languageForCountry implements GetLanguageForCountry for already parsed browser languages.
+func languageForCountry(cfg *Config, langInfo *LanguageInfo, countryCode string, availableSiteLanguages []string) string {
 	if cfg == nil || countryCode == "" {
 		return ""
 	}
 
 	// Check if country is actually mapped
 	countryKey := strings.ToUpper(countryCode)
-	_, exists := cfg.CountryToLanguageMap[countryKey]
-	if !exists {
+	if _, exists := cfg.countryLanguages(countryKey); !exists {
 		return ""
 	}
 
@@ -274,8 +288,6 @@ func GetLanguageForCountry(r *http.Request, cfg *Config, countryCode string, ava
 		return ""
 	}
 
-	langInfo := ParseLanguageInfo(r)
-
 	if len(availableSiteLanguages) > 0 {
 		// 1. Check preferred language
 		if langInfo.Default != "" {
@@ -310,7 +322,21 @@ func GetLanguageForCountry(r *http.Request, cfg *Config, countryCode string, ava
 }
 
 // ShouldSetLanguage returns true if the language cookie should be set (i.e., if no language cookie exists).
+// When r went through a middleware configured with WithLanguageCookie for cookieName, the cookie is
+// read as the middleware reads it: with a signer, tampered or expired cookies count as absent.
+// Without the middleware, or with WithContextKey, use ShouldSetSignedLanguage for signed cookies.
+//
+// Example:
+//
+//	handler := geolocation.Middleware(geolocation.WithLanguageCookie("lang", signer))(mux)
+//	// in a handler:
+//	if geolocation.ShouldSetLanguage(r, "lang") {
+//		geolocation.SetSignedCookie(w, signer, "lang", lang, nil)
+//	}
 func ShouldSetLanguage(r *http.Request, cookieName string) bool {
+	if info := RequestInfoFromContext(r.Context()); info != nil && info.langCookie == cookieName {
+		return info.ShouldSetLanguage()
+	}
 	cookie := GetCookie(r, cookieName)
 	return cookie == ""
 }
@@ -318,20 +344,13 @@ func ShouldSetLanguage(r *http.Request, cookieName string) bool {
 // IsLocalDevelopment checks if we're in a local development environment.
 // Returns true for localhost, local IPs, or missing Cloudflare headers.
 func IsLocalDevelopment(r *http.Request) bool {
-	loc := FromRequest(r)
-	ip := loc.IP
-	host := r.Host
-
-	// Check for localhost, local IPs, or missing Cloudflare headers
-	return ip == "" ||
-		ip == "127.0.0.1" ||
-		ip == "::1" ||
-		strings.HasPrefix(ip, "192.168.") ||
-		strings.HasPrefix(ip, "10.") ||
-		strings.HasPrefix(ip, "172.16.") ||
-		strings.Contains(host, "localhost") ||
-		strings.Contains(host, ".local") ||
-		r.Header.Get("CF-IPCountry") == ""
+	return isLocalRequest(NewRequest(r))
+}
+
+// IsInAppBrowser checks if the request comes from an in-app browser or a WebView.
+// It can be used as a middleware rule, e.g. to redirect such clients away from OAuth flows.
+func IsInAppBrowser(r *http.Request) bool {
+	return ParseClientInfo(r).IsEmbedded()
 }
 
 // Simulate creates a geolocation-enabled HTTP request with simulated Cloudflare headers
@@ -347,6 +366,26 @@ func Simulate(countryCode string, options *SimulationOptions) *http.Request {
 	return SimulateRequest(countryCode, options)
 }
 
+// IsLanguageTag reports whether s is a well-formed BCP 47 language tag such as de, pt-BR or zh-Hant-TW,
+// and safe to echo in templates and headers.
+func IsLanguageTag(s string) bool {
+	if s == "" || len(s) > 35 {
+		return false
+	}
+	for i, part := range strings.Split(s, "-") {
+		if len(part) == 0 || len(part) > 8 {
+			return false
+		}
+		for _, c := range part {
+			isLetter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			if !isLetter && (i == 0 || c < '0' || c > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // Helper functions
 
 // getLanguageCode extracts the language code from a locale string (e.g., "en-US" -> "en")
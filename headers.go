@@ -0,0 +1,164 @@
+package geolocation
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Headers provides read access to request headers independently of the HTTP framework.
+// http.Header implements it; adapters wrap fasthttp headers, gRPC metadata, and so on.
+// Keys are case-insensitive.
+type Headers interface {
+	Get(key string) string
+	Values(key string) []string
+}
+
+// Request is the framework-neutral view of an incoming request used by the middleware pipeline.
+// Adapters build it from their native request type so that every framework runs through
+// exactly the same extraction logic.
+type Request struct {
+	Header     Headers // Request headers
+	Method     string  // HTTP method, e.g. GET
+	Host       string  // Host the request was sent to
+	Path       string  // URL path
+	Route      string  // Route pattern matched by the router, e.g. /users/{id}, if known
+	RemoteAddr string  // Network address of the client, if known
+}
+
+// NewRequest returns the framework-neutral view of r. Headers are not copied.
+// Route is the http.ServeMux pattern (e.g. "GET /users/{id}") when r has already been routed.
+func NewRequest(r *http.Request) *Request {
+	req := &Request{
+		Header:     r.Header,
+		Method:     r.Method,
+		Host:       r.Host,
+		Route:      r.Pattern,
+		RemoteAddr: r.RemoteAddr,
+	}
+	if r.Header == nil {
+		req.Header = http.Header{}
+	}
+	if r.URL != nil {
+		req.Path = r.URL.Path
+	}
+	return req
+}
+
+// FromHeaders extracts geolocation info from Cloudflare headers.
+// It is the framework-neutral counterpart of FromRequest.
+// The IP address is anonymized according to SetIPPrivacy.
+func FromHeaders(h Headers) *Location {
+	return locationFromHeaders(h, nil)
+}
+
+// locationFromHeaders extracts the location, anonymizing the IP with privacy or,
+// when nil, the package-wide setting.
+func locationFromHeaders(h Headers, privacy *IPPrivacy) *Location {
+	loc := &Location{
+		IP:        h.Get("CF-Connecting-IP"),
+		Country:   h.Get("CF-IPCountry"),
+		Region:    h.Get("CF-Region-Code"),
+		Continent: h.Get("CF-IPContinent"),
+	}
+	if loc.Continent == "" {
+		loc.Continent = ContinentOf(loc.Country)
+	}
+	lat, latOK := parseCoordinate(h.Get("CF-IPLatitude"), 90)
+	lon, lonOK := parseCoordinate(h.Get("CF-IPLongitude"), 180)
+	if latOK && lonOK {
+		loc.Latitude, loc.Longitude = lat, lon
+	}
+	loc.TimeZone = resolveTimeZone(h.Get("CF-Timezone"), loc.Country)
+	anonymizeIP(loc, privacy)
+	return loc
+}
+
+// parseCoordinate parses a latitude or longitude in degrees, rejecting values outside ±limit.
+func parseCoordinate(s string, limit float64) (float64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(f >= -limit && f <= limit) { // also rejects NaN
+		return 0, false
+	}
+	return f, true
+}
+
+// ClientInfoFromHeaders parses the User-Agent header. See ParseClientInfo.
+func ClientInfoFromHeaders(h Headers) *ClientInfo {
+	return clientInfoFor(h.Get("User-Agent"))
+}
+
+// LanguageInfoFromHeaders parses the Accept-Language header. See ParseLanguageInfo.
+func LanguageInfoFromHeaders(h Headers) *LanguageInfo {
+	header := h.Get("Accept-Language")
+	if header == "" {
+		return &LanguageInfo{}
+	}
+	langs := parseAcceptLanguage(header)
+	defaultLang := ""
+	if len(langs) > 0 {
+		defaultLang = langs[0]
+	}
+	return &LanguageInfo{
+		Default:   defaultLang,
+		Supported: langs,
+	}
+}
+
+// GeoInfoFromHeaders returns all geolocation and client information. See GetGeoInfo.
+func GeoInfoFromHeaders(h Headers) *GeoInfo {
+	loc := FromHeaders(h)
+	info := newGeoInfo(loc, ClientInfoFromHeaders(h), LanguageInfoFromHeaders(h), ResolutionFromHeaders(h))
+	info.Compliance = NewCompliance(loc, h)
+	return info
+}
+
+// cookieFromHeaders returns the value of the named cookie from the Cookie headers, or empty string.
+func cookieFromHeaders(h Headers, name string) string {
+	lines := h.Values("Cookie")
+	if len(lines) == 0 {
+		return ""
+	}
+	r := http.Request{Header: http.Header{"Cookie": lines}}
+	return GetCookie(&r, name)
+}
+
+// isLocalRequest implements IsLocalDevelopment for the framework-neutral request view.
+func isLocalRequest(req *Request) bool {
+	ip := req.Header.Get("CF-Connecting-IP")
+	host := req.Host
+
+	// Check for localhost, local IPs, or missing Cloudflare headers
+	return ip == "" ||
+		ip == "127.0.0.1" ||
+		ip == "::1" ||
+		strings.HasPrefix(ip, "192.168.") ||
+		strings.HasPrefix(ip, "10.") ||
+		strings.HasPrefix(ip, "172.16.") ||
+		strings.Contains(host, "localhost") ||
+		strings.Contains(host, ".local") ||
+		req.Header.Get("CF-IPCountry") == ""
+}
+
+// overlayHeaders serves values from overrides before falling back to the wrapped Headers.
+type overlayHeaders struct {
+	Headers
+	overrides http.Header
+}
+
+func (o overlayHeaders) Get(key string) string {
+	if v := o.overrides.Values(key); len(v) > 0 {
+		return v[0]
+	}
+	return o.Headers.Get(key)
+}
+
+func (o overlayHeaders) Values(key string) []string {
+	if v := o.overrides.Values(key); len(v) > 0 {
+		return v
+	}
+	return o.Headers.Values(key)
+}
@@ -0,0 +1,79 @@
+package geolocation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// mapHeaders is a minimal Headers implementation, as a non-net/http framework would provide.
+type mapHeaders map[string]string
+
+func (m mapHeaders) Get(key string) string {
+	return m[strings.ToLower(key)]
+}
+
+func (m mapHeaders) Values(key string) []string {
+	if v, ok := m[strings.ToLower(key)]; ok {
+		return []string{v}
+	}
+	return nil
+}
+
+func TestFromHeaders_CustomImplementation(t *testing.T) {
+	h := mapHeaders{
+		"cf-connecting-ip": "1.2.3.4",
+		"cf-ipcountry":     "BG",
+		"user-agent":       "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
+		"accept-language":  "bg,en;q=0.5",
+		"cookie":           ScreenCookieName + "=w=1920&h=1080",
+	}
+	info := GeoInfoFromHeaders(h)
+	if info.IP != "1.2.3.4" || info.CountryCode != "BG" || info.Browser != "Firefox" ||
+		info.PreferredLanguage != "bg" || info.Resolution.Width != 1920 {
+		t.Errorf("unexpected GeoInfo from custom headers: %+v", info)
+	}
+}
+
+func TestGeoInfoFromHeaders_MatchesGetGeoInfo(t *testing.T) {
+	r := SimulateRequest("JP", nil)
+	r.AddCookie(&http.Cookie{Name: ScreenCookieName, Value: "w=390&h=844"})
+	got, want := GeoInfoFromHeaders(r.Header), GetGeoInfo(r)
+	if got.CountryCode != want.CountryCode || got.IP != want.IP || got.Browser != want.Browser || got.Resolution != want.Resolution {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://example.com/path?q=1", nil)
+	r.Header.Set("CF-IPCountry", "DE")
+	req := NewRequest(r)
+	if req.Method != "POST" || req.Host != "example.com" || req.Path != "/path" || req.RemoteAddr == "" {
+		t.Errorf("unexpected request view: %+v", req)
+	}
+	if req.Header.Get("CF-IPCountry") != "DE" {
+		t.Error("expected headers to be shared with the request")
+	}
+
+	req = NewRequest(&http.Request{})
+	if req.Header == nil || req.Header.Get("CF-IPCountry") != "" {
+		t.Error("expected empty headers for a bare request")
+	}
+}
+
+func TestOverlayHeaders(t *testing.T) {
+	base := http.Header{}
+	base.Set("CF-IPCountry", "BG")
+	base.Set("User-Agent", "Real")
+	h := simulateHeaders(base, "fr")
+	if h.Get("CF-IPCountry") != "FR" || h.Get("User-Agent") != "Real" {
+		t.Errorf("unexpected overlay values: %q %q", h.Get("CF-IPCountry"), h.Get("User-Agent"))
+	}
+	if v := h.Values("CF-IPCountry"); len(v) != 1 || v[0] != "FR" {
+		t.Errorf("unexpected overlay values: %v", v)
+	}
+	if v := h.Values("User-Agent"); len(v) != 1 || v[0] != "Real" {
+		t.Errorf("unexpected base values: %v", v)
+	}
+}
@@ -8,13 +8,125 @@ import (
 // contextKey is used for storing location in context.
 type contextKey struct{}
 
+// requestInfoKey is used for storing the lazily computed RequestInfo in context.
+type requestInfoKey struct{}
+
+// namedKey is used for storing values under a custom key set with WithContextKey.
+type namedKey string
+
 // HTTPMiddleware attaches geolocation info to the request context.
+// The full GeoInfo is available lazily through GeoInfoFromContext.
 func HTTPMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		loc := FromRequest(r)
-		ctx := context.WithValue(r.Context(), contextKey{}, loc)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	return Middleware()(next)
+}
+
+// GeoInfoMiddleware attaches the Location and a lazily computed GeoInfo to the request context.
+// opts selects which parts of GeoInfo are computed; parts are only parsed when first accessed.
+//
+// Example:
+//
+//	mux.Handle("/", geolocation.GeoInfoMiddleware(geolocation.GeoInfoOptions{Language: true})(handler))
+func GeoInfoMiddleware(opts GeoInfoOptions) func(http.Handler) http.Handler {
+	return Middleware(WithGeoInfo(opts))
+}
+
+// Middleware returns a net/http middleware configured with opts.
+// Without options it behaves like HTTPMiddleware.
+//
+// Example:
+//
+//	mw := geolocation.Middleware(
+//		geolocation.WithSkipPaths("/health", "/static/*"),
+//		geolocation.WithFallbackCountry("US"),
+//	)
+//	http.ListenAndServe(":8080", mw(mux))
+func Middleware(opts ...Option) func(http.Handler) http.Handler {
+	return RouteMiddleware(nil, opts...)
+}
+
+// RouteMiddleware is Middleware for routers that know the route pattern of a request.
+// route returns the pattern matching r, e.g. /users/{id}, for WithSkipRoutes and WithRoutePolicy;
+// when it is nil or returns empty string, the ServeMux pattern of r is used.
+// Adapters for net/http based routers pass their route lookup to it.
+//
+// Example:
+//
+//	mw := geolocation.RouteMiddleware(func(r *http.Request) string {
+//		_, pattern := mux.Handler(r)
+//		return pattern
+//	}, geolocation.WithSkipRoutes("GET /health"))
+func RouteMiddleware(route func(r *http.Request) string, opts ...Option) func(http.Handler) http.Handler {
+	o := NewMiddlewareOptions(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := NewRequest(r)
+			if route != nil {
+				if pattern := route(r); pattern != "" {
+					req.Route = pattern
+				}
+			}
+			o := o.ForRoute(req.Route)
+			if o.Skip(req) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			info, err := o.RequestInfo(req)
+			if err = o.HandleError(err); err != nil {
+				status := ErrorStatus(err)
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			if resp := o.CheckFence(req, info); resp != nil {
+				resp.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(newContext(r.Context(), o.ContextKey, info)))
+		})
+	}
+}
+
+// GeoFenceMiddleware returns a net/http middleware that blocks requests rejected by fence.
+// opts configure extraction as for Middleware.
+//
+// Example:
+//
+//	fence, err := geolocation.LoadGeoFence("config.yaml")
+//	handler := geolocation.GeoFenceMiddleware(fence, geolocation.WithSkipPaths("/health"))(mux)
+func GeoFenceMiddleware(fence *GeoFence, opts ...Option) func(http.Handler) http.Handler {
+	return Middleware(append([]Option{WithGeoFence(fence)}, opts...)...)
+}
+
+// AreasMiddleware returns a net/http middleware that stores the IDs of the areas containing
+// the visitor's coordinates in the request context. See AreasFromContext.
+//
+// Example:
+//
+//	areas, err := geolocation.LoadAreas("delivery-zones.geojson")
+//	handler := geolocation.AreasMiddleware(areas)(mux)
+func AreasMiddleware(areas *AreaIndex, opts ...Option) func(http.Handler) http.Handler {
+	return Middleware(append([]Option{WithAreas(areas)}, opts...)...)
+}
+
+// NewContext returns a copy of ctx carrying info and its Location.
+func NewContext(ctx context.Context, info *RequestInfo) context.Context {
+	return newContext(ctx, DefaultContextKey, info)
+}
+
+// NewContextKey returns a copy of ctx carrying info and its Location under key,
+// as read back by FromContextKey and GeoInfoFromContextKey.
+// Adapters for net/http based routers use it to share this package's context keys.
+func NewContextKey(ctx context.Context, key string, info *RequestInfo) context.Context {
+	return newContext(ctx, key, info)
+}
+
+// newContext stores info under the default keys, or under key if it was customized.
+func newContext(ctx context.Context, key string, info *RequestInfo) context.Context {
+	if key != DefaultContextKey {
+		ctx = context.WithValue(ctx, namedKey(key), info.Location())
+		return context.WithValue(ctx, namedKey(key+"_info"), info)
+	}
+	ctx = context.WithValue(ctx, contextKey{}, info.Location())
+	return context.WithValue(ctx, requestInfoKey{}, info)
 }
 
 // FromContext retrieves the Location from context.
@@ -22,3 +134,71 @@ func FromContext(ctx context.Context) *Location {
 	loc, _ := ctx.Value(contextKey{}).(*Location)
 	return loc
 }
+
+// FromContextKey retrieves the Location stored under a key set with WithContextKey.
+func FromContextKey(ctx context.Context, key string) *Location {
+	if key == DefaultContextKey {
+		return FromContext(ctx)
+	}
+	loc, _ := ctx.Value(namedKey(key)).(*Location)
+	return loc
+}
+
+// RequestInfoFromContext retrieves the lazily computed RequestInfo from context.
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
+
+// requestInfoFromContextKey retrieves the RequestInfo stored under key.
+func requestInfoFromContextKey(ctx context.Context, key string) *RequestInfo {
+	if key == DefaultContextKey {
+		return RequestInfoFromContext(ctx)
+	}
+	info, _ := ctx.Value(namedKey(key + "_info")).(*RequestInfo)
+	return info
+}
+
+// AreasFromContext returns the IDs of the areas containing the visitor's coordinates,
+// as configured with WithAreas. Returns nil if there are none.
+func AreasFromContext(ctx context.Context) []string {
+	if info := RequestInfoFromContext(ctx); info != nil {
+		return info.Areas()
+	}
+	return nil
+}
+
+// NearestPlaceFromContext returns the place nearest to the visitor, as configured with
+// WithNearestPlace. Returns nil if there is none.
+func NearestPlaceFromContext(ctx context.Context) *RankedPlace {
+	if info := RequestInfoFromContext(ctx); info != nil {
+		return info.NearestPlace()
+	}
+	return nil
+}
+
+// ComplianceFromContext returns the privacy rules and signals for the request in context.
+// Returns nil if no middleware attached them or the part is disabled.
+func ComplianceFromContext(ctx context.Context) *Compliance {
+	if info := RequestInfoFromContext(ctx); info != nil {
+		return info.Compliance()
+	}
+	return nil
+}
+
+// GeoInfoFromContext retrieves the full GeoInfo from context, computing it on first access.
+// Returns nil if no middleware attached it.
+func GeoInfoFromContext(ctx context.Context) *GeoInfo {
+	if info := RequestInfoFromContext(ctx); info != nil {
+		return info.GeoInfo()
+	}
+	return nil
+}
+
+// GeoInfoFromContextKey retrieves the GeoInfo stored under a key set with WithContextKey.
+func GeoInfoFromContextKey(ctx context.Context, key string) *GeoInfo {
+	if info := requestInfoFromContextKey(ctx, key); info != nil {
+		return info.GeoInfo()
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -49,3 +50,64 @@ func TestFromContext_Nil(t *testing.T) {
 		t.Error("expected nil location from empty context")
 	}
 }
+
+func TestHTTPMiddleware_GeoInfo(t *testing.T) {
+	h := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := GeoInfoFromContext(r.Context())
+		if info == nil || info.CountryCode != "DE" || info.PreferredLanguage != "de-DE" || info.Browser != "Chrome" {
+			t.Errorf("unexpected GeoInfo in context: %+v", info)
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "DE")
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoInfoMiddleware_Options(t *testing.T) {
+	h := GeoInfoMiddleware(GeoInfoOptions{ClientInfo: true})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loc := FromContext(r.Context()); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected location in context, got %+v", loc)
+		}
+		info := GeoInfoFromContext(r.Context())
+		if info == nil || info.Browser == "" || info.PreferredLanguage != "" {
+			t.Errorf("expected only client info to be computed, got %+v", info)
+		}
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	req.Header.Set("Accept-Language", "bg")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestGeoInfoFromContext_Nil(t *testing.T) {
+	if info := GeoInfoFromContext(context.Background()); info != nil {
+		t.Error("expected nil GeoInfo from empty context")
+	}
+	if info := RequestInfoFromContext(context.Background()); info != nil {
+		t.Error("expected nil RequestInfo from empty context")
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	route := func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/users/") {
+			return "/users/{id}"
+		}
+		return ""
+	}
+	var skipped bool
+	h := RouteMiddleware(route, WithSkipRoutes("/users/{id}"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		skipped = FromContext(r.Context()) == nil
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/42", nil))
+	if !skipped {
+		t.Error("expected the resolved route to be skipped")
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/other", nil))
+	if skipped {
+		t.Error("expected other routes to get a location")
+	}
+}
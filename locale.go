@@ -0,0 +1,125 @@
+package geolocation
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MeasurementSystem is the system of units in everyday use in a country, as defined by CLDR.
+type MeasurementSystem string
+
+// Measurement systems.
+const (
+	MeasurementMetric MeasurementSystem = "metric"
+	MeasurementUS     MeasurementSystem = "US" // Imperial units with US customary volumes
+	MeasurementUK     MeasurementSystem = "UK" // Metric with miles, pints and stones in daily use
+)
+
+// firstDays maps the first_day column of the country registry to weekdays.
+var firstDays = map[string]time.Weekday{
+	"mon": time.Monday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+	"sun": time.Sunday,
+}
+
+//go:embed assets/numbers.csv
+var numbersCSV []byte
+
+// numberSymbols holds the decimal and grouping separators of a locale.
+type numberSymbols struct {
+	decimal, group string
+}
+
+// numbers parses the embedded separators, keyed by language or language-REGION, on first use.
+var numbers = sync.OnceValue(func() map[string]numberSymbols {
+	records, err := csv.NewReader(bytes.NewReader(numbersCSV)).ReadAll()
+	if err != nil {
+		panic("geolocation: invalid embedded number data: " + err.Error())
+	}
+	m := make(map[string]numberSymbols, len(records)-1)
+	for _, rec := range records[1:] {
+		m[rec[0]] = numberSymbols{decimal: rec[1], group: rec[2]}
+	}
+	return m
+})
+
+// LocalePreferences holds formatting conventions for a visitor, derived from a subset of CLDR data.
+type LocalePreferences struct {
+	Locale            string            `json:"locale"`             // e.g. de-CH
+	Language          string            `json:"language"`           // e.g. de
+	Country           string            `json:"country"`            // e.g. CH
+	Currency          string            `json:"currency"`           // ISO 4217 code, e.g. CHF
+	MeasurementSystem MeasurementSystem `json:"measurement_system"` // metric, US or UK
+	FirstDayOfWeek    time.Weekday      `json:"first_day_of_week"`  // 0 is Sunday
+	DecimalSeparator  string            `json:"decimal_separator"`  // e.g. .
+	GroupingSeparator string            `json:"grouping_separator"` // e.g. '
+}
+
+// Metric reports whether the visitor uses metric units.
+func (p *LocalePreferences) Metric() bool {
+	return p.MeasurementSystem == MeasurementMetric
+}
+
+// NewLocalePreferences returns the preferences for a language spoken in a country.
+// Currency, units and the first day of the week follow the country; separators follow the
+// language as used in that country, e.g. de in CH groups with an apostrophe while de in DE uses a dot.
+// language may carry a region (de-CH), which is used when country is empty.
+// Unknown countries get metric units, Monday and no currency; unknown languages get . and , separators.
+//
+// Example:
+//
+//	p := geolocation.NewLocalePreferences("de", "CH")
+//	fmt.Println(p.Currency, p.GroupingSeparator, p.FirstDayOfWeek, p.Metric()) // CHF ' Monday true
+func NewLocalePreferences(language, country string) *LocalePreferences {
+	lang, region, _ := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-")
+	lang = strings.ToLower(lang)
+	if country == "" {
+		country = region
+	}
+	country = strings.ToUpper(country)
+
+	p := &LocalePreferences{
+		Language:          lang,
+		Country:           country,
+		MeasurementSystem: MeasurementMetric,
+		FirstDayOfWeek:    time.Monday,
+	}
+	switch {
+	case lang != "" && country != "":
+		p.Locale = lang + "-" + country
+	case lang != "":
+		p.Locale = lang
+	}
+	if c, ok := LookupCountry(country); ok {
+		p.Currency = c.Currency
+		p.MeasurementSystem = c.MeasurementSystem
+		p.FirstDayOfWeek = c.FirstDayOfWeek
+	}
+
+	symbols, ok := numbers()[p.Locale]
+	if !ok {
+		if symbols, ok = numbers()[lang]; !ok {
+			symbols = numbers()["root"]
+		}
+	}
+	p.DecimalSeparator, p.GroupingSeparator = symbols.decimal, symbols.group
+	return p
+}
+
+// LocalePreferencesFromRequest derives preferences from the visitor's preferred language
+// (Accept-Language) and detected country (CF-IPCountry).
+// Use NewLocalePreferences with the language negotiated by GetLanguageForCountry to match the site's language.
+//
+// Example:
+//
+//	prefs := geolocation.LocalePreferencesFromRequest(r)
+//	fmt.Println(prefs.Currency, prefs.DecimalSeparator)
+func LocalePreferencesFromRequest(r *http.Request) *LocalePreferences {
+	return NewLocalePreferences(ParseLanguageInfo(r).Default, FromRequest(r).Country)
+}
@@ -0,0 +1,72 @@
+package geolocation
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewLocalePreferences(t *testing.T) {
+	tests := []struct {
+		language, country string
+		want              LocalePreferences
+	}{
+		{"de", "CH", LocalePreferences{"de-CH", "de", "CH", "CHF", MeasurementMetric, time.Monday, ".", "'"}},
+		{"de", "DE", LocalePreferences{"de-DE", "de", "DE", "EUR", MeasurementMetric, time.Monday, ",", "."}},
+		{"fr", "CH", LocalePreferences{"fr-CH", "fr", "CH", "CHF", MeasurementMetric, time.Monday, ",", "\u202f"}},
+		{"en-GB", "US", LocalePreferences{"en-US", "en", "US", "USD", MeasurementUS, time.Sunday, ".", ","}},
+		{"en", "GB", LocalePreferences{"en-GB", "en", "GB", "GBP", MeasurementUK, time.Monday, ".", ","}},
+		{"ar", "AE", LocalePreferences{"ar-AE", "ar", "AE", "AED", MeasurementMetric, time.Saturday, ".", ","}},
+		{"es", "MX", LocalePreferences{"es-MX", "es", "MX", "MXN", MeasurementMetric, time.Sunday, ".", ","}},
+		{"pt_BR", "", LocalePreferences{"pt-BR", "pt", "BR", "BRL", MeasurementMetric, time.Sunday, ",", "."}},
+		{"bg", "bg", LocalePreferences{"bg-BG", "bg", "BG", "EUR", MeasurementMetric, time.Monday, ",", "\u00a0"}},
+		{"xx", "XX", LocalePreferences{"xx-XX", "xx", "XX", "", MeasurementMetric, time.Monday, ".", ","}},
+		{"", "", LocalePreferences{"", "", "", "", MeasurementMetric, time.Monday, ".", ","}},
+	}
+	for _, tt := range tests {
+		got := NewLocalePreferences(tt.language, tt.country)
+		if *got != tt.want {
+			t.Errorf("NewLocalePreferences(%q, %q) = %+v, want %+v", tt.language, tt.country, *got, tt.want)
+		}
+	}
+}
+
+func TestLocalePreferences_Metric(t *testing.T) {
+	if !NewLocalePreferences("de", "CH").Metric() {
+		t.Error("expected CH to be metric")
+	}
+	if NewLocalePreferences("en", "US").Metric() || NewLocalePreferences("en", "GB").Metric() {
+		t.Error("expected US and GB not to be fully metric")
+	}
+}
+
+func TestCountryLocaleData(t *testing.T) {
+	for _, code := range Countries() {
+		c, _ := LookupCountry(code)
+		if len(c.Currency) != 3 {
+			t.Errorf("country %s has invalid currency %q", code, c.Currency)
+		}
+		switch c.MeasurementSystem {
+		case MeasurementMetric, MeasurementUS, MeasurementUK:
+		default:
+			t.Errorf("country %s has invalid measurement system %q", code, c.MeasurementSystem)
+		}
+	}
+}
+
+func TestLocalePreferencesFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "CH")
+	r.Header.Set("Accept-Language", "it-IT,it;q=0.9,en;q=0.8")
+	p := LocalePreferencesFromRequest(r)
+	if p.Locale != "it-CH" || p.Currency != "CHF" || p.DecimalSeparator != "." || p.GroupingSeparator != "'" {
+		t.Errorf("unexpected preferences: %+v", p)
+	}
+
+	// Without a detected country, the browser's region is used.
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept-Language", "ja-JP")
+	if p := LocalePreferencesFromRequest(r); p.Currency != "JPY" || p.FirstDayOfWeek != time.Sunday {
+		t.Errorf("unexpected preferences without country: %+v", p)
+	}
+}
@@ -0,0 +1,375 @@
+package geolocation
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultContextKey is the key under which adapters store the Location.
+// The RequestInfo is stored under the same key with an "_info" suffix.
+const DefaultContextKey = "geolocation"
+
+// ErrLocationUnavailable is reported to the error handler when no country could be determined.
+var ErrLocationUnavailable = errors.New("geolocation: location unavailable")
+
+// StatusError is an error carrying the HTTP status code a middleware should respond with.
+type StatusError struct {
+	Code int   // HTTP status code
+	Err  error // Underlying error
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorStatus returns the HTTP status code for err: the code of a wrapped StatusError,
+// or 500 Internal Server Error otherwise.
+func ErrorStatus(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code
+	}
+	return http.StatusInternalServerError
+}
+
+// Option configures a geolocation middleware. The same options are accepted by
+// Middleware in this package and by every adapter.
+type Option func(*MiddlewareOptions)
+
+// MiddlewareOptions holds the settings shared by all middlewares.
+// Adapters build it with NewMiddlewareOptions; applications use the With* options.
+type MiddlewareOptions struct {
+	GeoInfo         GeoInfoOptions                // Which parts of GeoInfo are computed
+	SkipPaths       []string                      // Paths that bypass the middleware
+	SkipRoutes      []string                      // Route patterns that bypass the middleware
+	Skipper         func(r *Request) bool         // Custom skip rule
+	ContextKey      string                        // Key used to store the Location
+	FallbackCountry string                        // Country used when CF-IPCountry is missing
+	Simulate        bool                          // Simulate Cloudflare headers in local development
+	SimulateCountry string                        // Country used for simulation
+	ErrorHandler    func(err error) error         // Decides whether errors abort the request
+	Areas           *AreaIndex                    // Areas matched against the request's coordinates
+	Places          []Place                       // Places ranked by distance from the visitor
+	IPPrivacy       *IPPrivacy                    // IP anonymization; nil uses the package-wide setting
+	LanguageCookie  string                        // Cookie holding the visitor's chosen language
+	CookieSigner    *CookieSigner                 // Verifies LanguageCookie; nil reads it unsigned
+	GeoFence        *GeoFence                     // Access rules checked after extraction
+	Providers       []Provider                    // Trusted location sources consulted before Cloudflare headers
+	Routes          map[string]*MiddlewareOptions // Per-route options keyed by route pattern
+	Config          ConfigProvider                // Site configuration, read once per request
+
+	routeOptions map[string][]Option // Collected by WithRoutePolicy, resolved into Routes
+}
+
+// NewMiddlewareOptions applies opts on top of the defaults: all GeoInfo parts enabled,
+// DefaultContextKey, no skipping, no fallback, no simulation and errors ignored.
+func NewMiddlewareOptions(opts ...Option) *MiddlewareOptions {
+	o := &MiddlewareOptions{
+		GeoInfo:    DefaultGeoInfoOptions(),
+		ContextKey: DefaultContextKey,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	for pattern, ropts := range o.routeOptions {
+		ro := *o
+		ro.SkipPaths = slices.Clip(o.SkipPaths)
+		ro.SkipRoutes = slices.Clip(o.SkipRoutes)
+		ro.Providers = slices.Clip(o.Providers)
+		ro.Routes, ro.routeOptions = nil, nil // route policies do not nest
+		for _, opt := range ropts {
+			opt(&ro)
+		}
+		ro.routeOptions = nil
+		if o.Routes == nil {
+			o.Routes = make(map[string]*MiddlewareOptions)
+		}
+		o.Routes[pattern] = &ro
+	}
+	o.routeOptions = nil
+	return o
+}
+
+// WithGeoInfo selects which parts of GeoInfo are computed.
+func WithGeoInfo(opts GeoInfoOptions) Option {
+	return func(o *MiddlewareOptions) {
+		o.GeoInfo = opts
+	}
+}
+
+// WithSkipPaths skips the middleware for the given paths.
+// A path ending in "*" matches every path with that prefix.
+func WithSkipPaths(paths ...string) Option {
+	return func(o *MiddlewareOptions) {
+		o.SkipPaths = append(o.SkipPaths, paths...)
+	}
+}
+
+// WithSkipRoutes skips the middleware for requests matched to one of the given route patterns,
+// e.g. "/users/{id}". Routes are only known to adapters for routers that match before
+// running middleware, such as chi, gorilla/mux and http.ServeMux.
+func WithSkipRoutes(patterns ...string) Option {
+	return func(o *MiddlewareOptions) {
+		o.SkipRoutes = append(o.SkipRoutes, patterns...)
+	}
+}
+
+// WithRoutePolicy applies opts on top of the other options for requests matched to pattern.
+// Options not set in opts are inherited, regardless of the order in which they are given.
+//
+// Example:
+//
+//	mw := chiadapter.Middleware(
+//		geolocation.WithFallbackCountry("US"),
+//		geolocation.WithRoutePolicy("/checkout/{id}", geolocation.WithErrorHandler(rejectUnknown)),
+//	)
+func WithRoutePolicy(pattern string, opts ...Option) Option {
+	return func(o *MiddlewareOptions) {
+		if o.routeOptions == nil {
+			o.routeOptions = make(map[string][]Option)
+		}
+		o.routeOptions[pattern] = append(o.routeOptions[pattern], opts...)
+	}
+}
+
+// WithAreas matches the request's coordinates against areas. The matched area IDs are computed
+// on first access through RequestInfo.Areas or AreasFromContext.
+func WithAreas(areas *AreaIndex) Option {
+	return func(o *MiddlewareOptions) {
+		o.Areas = areas
+	}
+}
+
+// WithNearestPlace ranks places by distance from the visitor. The nearest one is computed on first
+// access through RequestInfo.NearestPlace or NearestPlaceFromContext, from the request's coordinates
+// or, when those are missing, the centroid of the visitor's country.
+//
+// Example:
+//
+//	geolocation.WithNearestPlace([]geolocation.Place{
+//		{Name: "eu-central", Coordinates: geolocation.Coordinates{Latitude: 50.11, Longitude: 8.68}},
+//		{Name: "us-east", Coordinates: geolocation.Coordinates{Latitude: 38.9, Longitude: -77.04}},
+//	})
+func WithNearestPlace(places []Place) Option {
+	return func(o *MiddlewareOptions) {
+		o.Places = places
+	}
+}
+
+// WithIPPrivacy anonymizes the IP address stored in context for this middleware,
+// overriding the package-wide setting from SetIPPrivacy.
+//
+// Example:
+//
+//	r.Use(ginadapter.Middleware(geolocation.WithIPPrivacy(geolocation.IPPrivacy{Mode: geolocation.IPModeDrop})))
+func WithIPPrivacy(p IPPrivacy) Option {
+	return func(o *MiddlewareOptions) {
+		o.IPPrivacy = &p
+	}
+}
+
+// WithLanguageCookie makes the language stored in the named cookie the visitor's preferred language
+// in LanguageInfo and GeoInfo, ahead of Accept-Language. With a signer, only cookies set with
+// SetSignedCookie are accepted; tampered or expired cookies are ignored as if absent.
+// Values that are not language tags are always ignored.
+//
+// Example:
+//
+//	signer := geolocation.NewCookieSigner(key)
+//	r.Use(ginadapter.Middleware(geolocation.WithLanguageCookie(cfg.CookieName, signer)))
+func WithLanguageCookie(name string, signer *CookieSigner) Option {
+	return func(o *MiddlewareOptions) {
+		o.LanguageCookie = name
+		o.CookieSigner = signer
+	}
+}
+
+// WithConfig makes the site configuration available to handlers through RequestInfo.Config and
+// RequestInfo.SiteLanguage. The config is read once per request, so with a ConfigWatcher every request
+// sees one consistent version while reloads take effect for the next request. Config.CookieName is
+// used as the language cookie unless WithLanguageCookie names another.
+//
+// Example:
+//
+//	watcher, err := geolocation.NewConfigWatcher("config.yaml")
+//	go watcher.Watch(ctx, 0)
+//	handler := geolocation.Middleware(geolocation.WithConfig(watcher))(mux)
+func WithConfig(p ConfigProvider) Option {
+	return func(o *MiddlewareOptions) {
+		o.Config = p
+	}
+}
+
+// WithGeoFence blocks requests rejected by fence. Skipped requests bypass the fence too.
+func WithGeoFence(fence *GeoFence) Option {
+	return func(o *MiddlewareOptions) {
+		o.GeoFence = fence
+	}
+}
+
+// WithSkipper skips the middleware for requests where fn returns true.
+// fn receives the framework-neutral request view, so the same rule works with every adapter.
+func WithSkipper(fn func(r *Request) bool) Option {
+	return func(o *MiddlewareOptions) {
+		o.Skipper = fn
+	}
+}
+
+// WithContextKey stores the Location under key instead of DefaultContextKey.
+// Use the adapters' FromContextKey helpers to read it back.
+func WithContextKey(key string) Option {
+	return func(o *MiddlewareOptions) {
+		if key != "" {
+			o.ContextKey = key
+		}
+	}
+}
+
+// WithFallbackCountry uses country when the request carries no CF-IPCountry header.
+func WithFallbackCountry(country string) Option {
+	return func(o *MiddlewareOptions) {
+		o.FallbackCountry = strings.ToUpper(country)
+	}
+}
+
+// WithSimulation simulates Cloudflare headers for the given country when
+// IsLocalDevelopment reports a local request. An empty country simulates US.
+func WithSimulation(country string) Option {
+	return func(o *MiddlewareOptions) {
+		o.Simulate = true
+		o.SimulateCountry = strings.ToUpper(country)
+	}
+}
+
+// WithErrorHandler sets the function called with middleware errors such as ErrLocationUnavailable.
+// Returning nil continues the request; returning an error aborts it with ErrorStatus(err).
+func WithErrorHandler(fn func(err error) error) Option {
+	return func(o *MiddlewareOptions) {
+		o.ErrorHandler = fn
+	}
+}
+
+// InfoKey returns the key under which the RequestInfo is stored.
+func (o *MiddlewareOptions) InfoKey() string {
+	return o.ContextKey + "_info"
+}
+
+// UsesRoutes reports whether any option matches the route pattern of a request: WithSkipRoutes,
+// WithRoutePolicy or geo fence rules with paths. Adapters for which looking up the route is
+// costly skip the lookup when it is false.
+func (o *MiddlewareOptions) UsesRoutes() bool {
+	return len(o.SkipRoutes) > 0 || len(o.Routes) > 0 || o.GeoFence.hasPaths()
+}
+
+// ForRoute returns the options for a request matched to route:
+// the policy registered with WithRoutePolicy, or o itself.
+func (o *MiddlewareOptions) ForRoute(route string) *MiddlewareOptions {
+	if ro, ok := o.Routes[route]; ok && route != "" {
+		return ro
+	}
+	return o
+}
+
+// Skip reports whether the middleware should skip req.
+func (o *MiddlewareOptions) Skip(req *Request) bool {
+	if o.Skipper != nil && o.Skipper(req) {
+		return true
+	}
+	if req.Route != "" && slices.Contains(o.SkipRoutes, req.Route) {
+		return true
+	}
+	return matchPath(o.SkipPaths, req.Path)
+}
+
+// matchPath reports whether path matches one of patterns.
+// A pattern ending in "*" matches every path with that prefix.
+func matchPath(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestInfo runs the shared extraction pipeline for req: providers, development simulation,
+// location extraction and the fallback country. The returned RequestInfo is always usable;
+// the error is ErrLocationUnavailable when no country could be determined.
+func (o *MiddlewareOptions) RequestInfo(req *Request) (*RequestInfo, error) {
+	h := req.Header
+	for _, p := range o.Providers {
+		if ph, ok := p.Provide(req); ok {
+			provided := *req
+			provided.Header = ph
+			req, h = &provided, ph
+			break
+		}
+	}
+	if o.Simulate && isLocalRequest(req) {
+		h = simulateHeaders(h, o.SimulateCountry)
+	}
+	info := NewRequestInfoFromHeaders(h, o.GeoInfo)
+	info.areaIndex = o.Areas
+	info.places = o.Places
+	info.ipPrivacy = o.IPPrivacy
+	info.langCookie, info.langSigner = o.LanguageCookie, o.CookieSigner
+	if o.Config != nil {
+		info.config = o.Config.Config()
+		if info.langCookie == "" && info.config != nil {
+			info.langCookie = info.config.view().CookieName
+		}
+	}
+	loc := info.Location()
+	if loc.Country == "" && o.FallbackCountry != "" {
+		loc.Country = o.FallbackCountry
+		loc.Continent = ContinentOf(loc.Country)
+		loc.TimeZone = TimeZoneOf(loc.Country)
+	}
+	if loc.Country == "" {
+		return info, ErrLocationUnavailable
+	}
+	return info, nil
+}
+
+// CheckFence applies the GeoFence to req. It returns nil if the request may continue,
+// or the response to send if it is blocked.
+func (o *MiddlewareOptions) CheckFence(req *Request, info *RequestInfo) *FenceResponse {
+	if o.GeoFence == nil {
+		return nil
+	}
+	return o.GeoFence.Check(req, info.Location())
+}
+
+// HandleError passes err to the ErrorHandler. A nil result means the request should continue.
+func (o *MiddlewareOptions) HandleError(err error) error {
+	if err == nil || o.ErrorHandler == nil {
+		return nil
+	}
+	return o.ErrorHandler(err)
+}
+
+// simulateHeaders overlays simulated Cloudflare location headers on h.
+// The client's own User-Agent and Accept-Language are preserved.
+func simulateHeaders(h Headers, country string) Headers {
+	if country == "" {
+		country = "US"
+	}
+	fake := FakeCloudflareHeaders(country, nil)
+	overrides := http.Header{}
+	for _, key := range []string{"CF-IPCountry", "CF-Connecting-IP", "CF-Ray", "CF-Timezone"} {
+		overrides.Set(key, fake[key])
+	}
+	return overlayHeaders{Headers: h, overrides: overrides}
+}
@@ -0,0 +1,268 @@
+package geolocation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewMiddlewareOptions_Defaults(t *testing.T) {
+	o := NewMiddlewareOptions()
+	if o.ContextKey != DefaultContextKey || o.GeoInfo != DefaultGeoInfoOptions() || o.Simulate || o.FallbackCountry != "" {
+		t.Errorf("unexpected defaults: %+v", o)
+	}
+	if o.InfoKey() != DefaultContextKey+"_info" {
+		t.Errorf("unexpected info key: %q", o.InfoKey())
+	}
+}
+
+func TestMiddlewareOptions_Skip(t *testing.T) {
+	o := NewMiddlewareOptions(
+		WithSkipPaths("/health", "/static/*"),
+		WithSkipper(func(r *Request) bool { return r.Method == http.MethodOptions }),
+	)
+	cases := []struct {
+		method, path string
+		skip         bool
+	}{
+		{"GET", "/health", true},
+		{"GET", "/healthz", false},
+		{"GET", "/static/app.js", true},
+		{"GET", "/api", false},
+		{"OPTIONS", "/api", true},
+	}
+	for _, c := range cases {
+		if got := o.Skip(NewRequest(httptest.NewRequest(c.method, c.path, nil))); got != c.skip {
+			t.Errorf("Skip(%s %s) = %v, want %v", c.method, c.path, got, c.skip)
+		}
+	}
+}
+
+func TestMiddlewareOptions_FallbackCountry(t *testing.T) {
+	o := NewMiddlewareOptions(WithFallbackCountry("us"))
+	info, err := o.RequestInfo(NewRequest(httptest.NewRequest("GET", "/", nil)))
+	if err != nil || info.Location().Country != "US" || info.GeoInfo().CountryCode != "US" {
+		t.Errorf("expected fallback country US, got %+v (err %v)", info.Location(), err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "BG")
+	info, _ = o.RequestInfo(NewRequest(r))
+	if info.Location().Country != "BG" {
+		t.Errorf("expected header country to win over fallback, got %q", info.Location().Country)
+	}
+}
+
+func TestMiddlewareOptions_LocationUnavailable(t *testing.T) {
+	o := NewMiddlewareOptions()
+	info, err := o.RequestInfo(NewRequest(httptest.NewRequest("GET", "/", nil)))
+	if info == nil || !errors.Is(err, ErrLocationUnavailable) {
+		t.Errorf("expected ErrLocationUnavailable, got %v", err)
+	}
+	if o.HandleError(err) != nil {
+		t.Error("expected errors to be ignored without an error handler")
+	}
+}
+
+func TestMiddlewareOptions_Simulation(t *testing.T) {
+	o := NewMiddlewareOptions(WithSimulation("de"))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", "Real Browser")
+	info, err := o.RequestInfo(NewRequest(r))
+	if err != nil || info.Location().Country != "DE" || info.Location().IP == "" {
+		t.Errorf("expected simulated DE location, got %+v (err %v)", info.Location(), err)
+	}
+	if r.Header.Get("CF-IPCountry") != "" {
+		t.Error("expected original request headers to be left untouched")
+	}
+
+	r = httptest.NewRequest("GET", "http://example.com/", nil)
+	r.Header.Set("CF-Connecting-IP", "8.8.8.8")
+	r.Header.Set("CF-IPCountry", "FR")
+	info, _ = o.RequestInfo(NewRequest(r))
+	if info.Location().Country != "FR" {
+		t.Errorf("expected real headers outside local development, got %q", info.Location().Country)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	if got := ErrorStatus(errors.New("boom")); got != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", got)
+	}
+	err := &StatusError{Code: http.StatusForbidden, Err: ErrLocationUnavailable}
+	if got := ErrorStatus(err); got != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", got)
+	}
+	if !errors.Is(err, ErrLocationUnavailable) || err.Error() != ErrLocationUnavailable.Error() {
+		t.Error("expected StatusError to wrap the underlying error")
+	}
+}
+
+func TestMiddleware_Options(t *testing.T) {
+	var called bool
+	h := Middleware(
+		WithSkipPaths("/health"),
+		WithContextKey("geo"),
+		WithErrorHandler(func(err error) error {
+			return &StatusError{Code: http.StatusForbidden, Err: err}
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.URL.Path == "/health" {
+			if FromContextKey(r.Context(), "geo") != nil {
+				t.Error("expected skipped request to have no location")
+			}
+			return
+		}
+		if loc := FromContextKey(r.Context(), "geo"); loc == nil || loc.Country != "BG" {
+			t.Errorf("expected location under custom key, got %+v", loc)
+		}
+		if info := GeoInfoFromContextKey(r.Context(), "geo"); info == nil || info.CountryCode != "BG" {
+			t.Errorf("expected GeoInfo under custom key, got %+v", info)
+		}
+		if FromContext(r.Context()) != nil {
+			t.Error("expected nothing under the default key")
+		}
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("CF-IPCountry", "BG")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !called {
+		t.Error("expected handler to be called")
+	}
+
+	called = false
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if !called {
+		t.Error("expected skipped request to reach the handler")
+	}
+
+	called = false
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if called || rec.Code != http.StatusForbidden {
+		t.Errorf("expected request without country to be rejected with 403, got %d", rec.Code)
+	}
+}
+
+func TestFromContextKey_Default(t *testing.T) {
+	h := Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if FromContextKey(r.Context(), DefaultContextKey) != FromContext(r.Context()) {
+			t.Error("expected default key lookups to match FromContext")
+		}
+		if GeoInfoFromContextKey(r.Context(), DefaultContextKey) == nil {
+			t.Error("expected GeoInfo under the default key")
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestMiddlewareOptions_Routes(t *testing.T) {
+	o := NewMiddlewareOptions(
+		WithRoutePolicy("/checkout/{id}", WithErrorHandler(func(err error) error {
+			return &StatusError{Code: http.StatusForbidden, Err: err}
+		})),
+		WithSkipRoutes("/health"),
+		WithFallbackCountry("us"),
+	)
+	ro := o.ForRoute("/checkout/{id}")
+	if ro == o || ro.ErrorHandler == nil || ro.FallbackCountry != "US" || len(ro.SkipRoutes) != 1 {
+		t.Errorf("expected route policy to inherit base options regardless of order, got %+v", ro)
+	}
+	if o.ErrorHandler != nil {
+		t.Error("expected route policy not to change the base options")
+	}
+	if o.ForRoute("/other") != o || o.ForRoute("") != o {
+		t.Error("expected unmatched routes to use the base options")
+	}
+
+	req := NewRequest(httptest.NewRequest("GET", "/health", nil))
+	if o.Skip(req) {
+		t.Error("expected an unrouted request not to match a skipped route")
+	}
+	req.Route = "/health"
+	if !o.Skip(req) {
+		t.Error("expected request matched to /health to be skipped")
+	}
+}
+
+// namedProvider provides its name as the country.
+type namedProvider string
+
+func (p namedProvider) Provide(*Request) (Headers, bool) {
+	return http.Header{"Cf-Ipcountry": {string(p)}}, true
+}
+
+func TestMiddlewareOptions_RouteProviders(t *testing.T) {
+	// Each route policy appends to the base providers; they must not share a backing array.
+	base := make([]Option, 0, 5)
+	for _, p := range []namedProvider{"AA", "BB", "CC"} {
+		base = append(base, WithProvider(p))
+	}
+	for range 20 {
+		o := NewMiddlewareOptions(append(base,
+			WithRoutePolicy("/x", WithProvider(namedProvider("XX"))),
+			WithRoutePolicy("/y", WithProvider(namedProvider("YY"))),
+		)...)
+		for route, want := range map[string]string{"/x": "XX", "/y": "YY"} {
+			providers := o.ForRoute(route).Providers
+			if len(providers) != 4 || providers[3] != namedProvider(want) {
+				t.Fatalf("route %s providers = %v, want last %s", route, providers, want)
+			}
+		}
+		if len(o.Providers) != 3 {
+			t.Fatalf("base providers = %v", o.Providers)
+		}
+	}
+}
+
+func TestMiddleware_ServeMuxPattern(t *testing.T) {
+	mw := Middleware(
+		WithSkipRoutes("GET /health"),
+		WithRoutePolicy("GET /users/{id}", WithContextKey("geo")),
+	)
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if FromContextKey(r.Context(), "geo") == nil || FromContext(r.Context()) != nil {
+			t.Error("expected route policy to store the location under its key")
+		}
+	})))
+	mux.Handle("GET /health", mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if FromContext(r.Context()) != nil {
+			t.Error("expected skipped route to have no location")
+		}
+	})))
+	for _, path := range []string{"/users/1", "/health"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+}
+
+func TestMiddlewareOptions_UsesRoutes(t *testing.T) {
+	pathFence, err := NewGeoFence(GeoFenceConfig{Rules: []FenceRule{{Paths: []string{"/admin/*"}, Mode: FenceDeny, Countries: []string{"CU"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	allFence, err := NewGeoFence(GeoFenceConfig{Rules: []FenceRule{{Mode: FenceDeny, Countries: []string{"CU"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		opts []Option
+		want bool
+	}{
+		{"defaults", nil, false},
+		{"skip paths", []Option{WithSkipPaths("/health")}, false},
+		{"fence without paths", []Option{WithGeoFence(allFence)}, false},
+		{"skip routes", []Option{WithSkipRoutes("/health")}, true},
+		{"route policy", []Option{WithRoutePolicy("/users/{id}", WithFallbackCountry("DE"))}, true},
+		{"fence with paths", []Option{WithGeoFence(pathFence)}, true},
+	}
+	for _, tt := range tests {
+		if got := NewMiddlewareOptions(tt.opts...).UsesRoutes(); got != tt.want {
+			t.Errorf("%s: UsesRoutes = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
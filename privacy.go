@@ -0,0 +1,125 @@
+package geolocation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// IPMode selects how the visitor's IP address is stored in Location, GeoInfo and context values.
+type IPMode int
+
+// IP privacy modes.
+const (
+	IPModeFull         IPMode = iota // Keep the IP address as received
+	IPModeTruncate                   // Zero the host part: IPv4 to /24, IPv6 to /48
+	IPModePseudonymize               // Replace the IP with a keyed HMAC, stable per key
+	IPModeDrop                       // Remove the IP address
+)
+
+// IPPrivacy configures IP anonymization.
+//
+// Example:
+//
+//	geolocation.SetIPPrivacy(&geolocation.IPPrivacy{Mode: geolocation.IPModeTruncate})
+//
+//	// Pseudonyms from the newest key; older keys still match during rotation
+//	p := &geolocation.IPPrivacy{Mode: geolocation.IPModePseudonymize, Keys: [][]byte{newKey, oldKey}}
+type IPPrivacy struct {
+	Mode IPMode
+	Keys [][]byte // HMAC keys for IPModePseudonymize, newest first. Only the first is used to pseudonymize.
+}
+
+// ipPrivacy is the package-wide privacy setting used when a middleware does not set its own.
+var ipPrivacy atomic.Pointer[IPPrivacy]
+
+// SetIPPrivacy installs the package-wide IP privacy setting used by FromRequest, GetGeoInfo and
+// every middleware without WithIPPrivacy. nil restores IPModeFull.
+func SetIPPrivacy(p *IPPrivacy) {
+	ipPrivacy.Store(p)
+}
+
+// Apply returns ip transformed according to the mode. Values that are not valid IP addresses
+// become empty in every mode but IPModeFull, as does pseudonymization without a key.
+func (p *IPPrivacy) Apply(ip string) string {
+	if p == nil || p.Mode == IPModeFull || ip == "" {
+		return ip
+	}
+	switch p.Mode {
+	case IPModeTruncate:
+		return TruncateIP(ip)
+	case IPModePseudonymize:
+		if len(p.Keys) == 0 {
+			return ""
+		}
+		return PseudonymizeIP(p.Keys[0], ip)
+	}
+	return ""
+}
+
+// Matches reports whether pseudonym was produced from ip by any of the keys.
+// Use it to look up visitors across a key rotation.
+func (p *IPPrivacy) Matches(ip, pseudonym string) bool {
+	if p == nil || pseudonym == "" {
+		return false
+	}
+	for _, key := range p.Keys {
+		if hmac.Equal([]byte(PseudonymizeIP(key, ip)), []byte(pseudonym)) {
+			return true
+		}
+	}
+	return false
+}
+
+// TruncateIP zeroes the host part of an address: IPv4 to /24 and IPv6 to /48.
+// IPv4-mapped IPv6 addresses are treated as IPv4. Returns an empty string for invalid addresses.
+//
+// Example:
+//
+//	geolocation.TruncateIP("203.0.113.42")          // 203.0.113.0
+//	geolocation.TruncateIP("2001:db8:abcd:12::1")   // 2001:db8:abcd::
+func TruncateIP(ip string) string {
+	addr, ok := parseIP(ip)
+	if !ok {
+		return ""
+	}
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix.Addr().String()
+}
+
+// PseudonymizeIP returns a hex-encoded HMAC-SHA256 of the normalized address under key,
+// truncated to 128 bits. The same address and key always give the same pseudonym, so visitors
+// can be counted without storing their address. Returns an empty string for invalid addresses.
+func PseudonymizeIP(key []byte, ip string) string {
+	addr, ok := parseIP(ip)
+	if !ok {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(addr.String()))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// parseIP parses an address without a zone, unmapping IPv4-mapped IPv6 addresses.
+func parseIP(ip string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil || addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// anonymizeIP applies p, or the package-wide setting when p is nil, to the location's IP.
+func anonymizeIP(loc *Location, p *IPPrivacy) {
+	if p == nil {
+		p = ipPrivacy.Load()
+	}
+	loc.IP = p.Apply(loc.IP)
+}
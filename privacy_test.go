@@ -0,0 +1,158 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTruncateIP(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.42":               "203.0.113.0",
+		" 203.0.113.42 ":             "203.0.113.0",
+		"::ffff:203.0.113.42":        "203.0.113.0",
+		"2001:db8:abcd:12:34::1":     "2001:db8:abcd::",
+		"2001:0db8:abcd:ffff:ffff::": "2001:db8:abcd::",
+		"fe80::1%eth0":               "",
+		"not-an-ip":                  "",
+		"":                           "",
+	}
+	for ip, want := range tests {
+		if got := TruncateIP(ip); got != want {
+			t.Errorf("TruncateIP(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestPseudonymizeIP(t *testing.T) {
+	key := []byte("secret")
+	a := PseudonymizeIP(key, "203.0.113.42")
+	if len(a) != 32 || strings.Contains(a, "203") {
+		t.Fatalf("unexpected pseudonym %q", a)
+	}
+	if PseudonymizeIP(key, "::ffff:203.0.113.42") != a {
+		t.Error("expected IPv4-mapped addresses to give the same pseudonym")
+	}
+	if PseudonymizeIP(key, "203.0.113.43") == a {
+		t.Error("expected different addresses to give different pseudonyms")
+	}
+	if PseudonymizeIP([]byte("other"), "203.0.113.42") == a {
+		t.Error("expected different keys to give different pseudonyms")
+	}
+	if PseudonymizeIP(key, "bogus") != "" {
+		t.Error("expected empty pseudonym for invalid address")
+	}
+}
+
+func TestIPPrivacy_Apply(t *testing.T) {
+	oldKey, newKey := []byte("old"), []byte("new")
+	tests := []struct {
+		name string
+		p    *IPPrivacy
+		want string
+	}{
+		{"nil", nil, "203.0.113.42"},
+		{"full", &IPPrivacy{Mode: IPModeFull}, "203.0.113.42"},
+		{"truncate", &IPPrivacy{Mode: IPModeTruncate}, "203.0.113.0"},
+		{"pseudonymize", &IPPrivacy{Mode: IPModePseudonymize, Keys: [][]byte{newKey, oldKey}}, PseudonymizeIP(newKey, "203.0.113.42")},
+		{"pseudonymize without key", &IPPrivacy{Mode: IPModePseudonymize}, ""},
+		{"drop", &IPPrivacy{Mode: IPModeDrop}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Apply("203.0.113.42"); got != tt.want {
+			t.Errorf("%s: Apply = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIPPrivacy_Matches_KeyRotation(t *testing.T) {
+	oldKey, newKey := []byte("old"), []byte("new")
+	before := &IPPrivacy{Mode: IPModePseudonymize, Keys: [][]byte{oldKey}}
+	after := &IPPrivacy{Mode: IPModePseudonymize, Keys: [][]byte{newKey, oldKey}}
+
+	stored := before.Apply("2001:db8::1")
+	if after.Apply("2001:db8::1") == stored {
+		t.Error("expected new pseudonyms after rotation")
+	}
+	if !after.Matches("2001:db8::1", stored) {
+		t.Error("expected pseudonyms from the old key to match during rotation")
+	}
+	if after.Matches("2001:db8::2", stored) || after.Matches("2001:db8::1", "") {
+		t.Error("unexpected match")
+	}
+	retired := &IPPrivacy{Mode: IPModePseudonymize, Keys: [][]byte{newKey}}
+	if retired.Matches("2001:db8::1", stored) {
+		t.Error("expected retired keys not to match")
+	}
+}
+
+func TestSetIPPrivacy(t *testing.T) {
+	SetIPPrivacy(&IPPrivacy{Mode: IPModeTruncate})
+	defer SetIPPrivacy(nil)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-Connecting-IP", "198.51.100.77")
+	r.Header.Set("CF-IPCountry", "DE")
+
+	if got := FromRequest(r).IP; got != "198.51.100.0" {
+		t.Errorf("FromRequest IP = %q", got)
+	}
+	data, _ := json.Marshal(GetGeoInfo(r))
+	if strings.Contains(string(data), "198.51.100.77") || !strings.Contains(string(data), `"ip":"198.51.100.0"`) {
+		t.Errorf("expected truncated IP in JSON, got %s", data)
+	}
+
+	var got string
+	HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context()).IP
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if got != "198.51.100.0" {
+		t.Errorf("context IP = %q", got)
+	}
+
+	SetIPPrivacy(nil)
+	if got := FromRequest(r).IP; got != "198.51.100.77" {
+		t.Errorf("expected full IP after reset, got %q", got)
+	}
+}
+
+func TestWithIPPrivacy(t *testing.T) {
+	SetIPPrivacy(&IPPrivacy{Mode: IPModeTruncate})
+	defer SetIPPrivacy(nil)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-Connecting-IP", "2001:db8:abcd:12::1")
+	r.Header.Set("CF-IPCountry", "DE")
+
+	var loc *Location
+	var info *GeoInfo
+	h := Middleware(WithIPPrivacy(IPPrivacy{Mode: IPModeDrop}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loc = FromContext(r.Context())
+		info = GeoInfoFromContext(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if loc.IP != "" || info.IP != "" {
+		t.Errorf("expected per-middleware drop to override global truncation, got %q and %q", loc.IP, info.IP)
+	}
+	if loc.Country != "DE" {
+		t.Errorf("expected the rest of the location to be kept, got %+v", loc)
+	}
+
+	// Route policies inherit the privacy mode and may override it.
+	mux := http.NewServeMux()
+	mux.Handle("/debug", Middleware(
+		WithIPPrivacy(IPPrivacy{Mode: IPModeDrop}),
+		WithRoutePolicy("/debug", WithIPPrivacy(IPPrivacy{Mode: IPModeFull})),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loc = FromContext(r.Context())
+	})))
+	r = httptest.NewRequest("GET", "/debug", nil)
+	r.Header.Set("CF-Connecting-IP", "2001:db8:abcd:12::1")
+	r.Header.Set("CF-IPCountry", "DE")
+	mux.ServeHTTP(httptest.NewRecorder(), r)
+	if loc.IP != "2001:db8:abcd:12::1" {
+		t.Errorf("expected route policy to keep the full IP, got %q", loc.IP)
+	}
+}
@@ -0,0 +1,191 @@
+package geolocation
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Internal headers used to propagate geolocation between services. See Transport.
+const (
+	HeaderGeoCountry   = "X-Geo-Country"   // Country code
+	HeaderGeoIP        = "X-Geo-IP"        // Client IP address
+	HeaderGeoLang      = "X-Geo-Lang"      // Client languages in order of preference, comma-separated
+	HeaderGeoTimestamp = "X-Geo-Timestamp" // Unix time the headers were signed
+	HeaderGeoSignature = "X-Geo-Signature" // Hex HMAC-SHA256 of the values above
+)
+
+// DefaultSignatureMaxAge is how old (or how far in the future) a signature may be before it is rejected.
+const DefaultSignatureMaxAge = 5 * time.Minute
+
+// Errors returned by SignedHeaderProvider.Verify.
+var (
+	ErrMissingSignature = errors.New("geolocation: missing geo signature")
+	ErrInvalidSignature = errors.New("geolocation: invalid geo signature")
+	ErrExpiredSignature = errors.New("geolocation: expired geo signature")
+)
+
+// geoHeaders lists the propagated headers, removed from outgoing requests before signing.
+var geoHeaders = []string{HeaderGeoCountry, HeaderGeoIP, HeaderGeoLang, HeaderGeoTimestamp, HeaderGeoSignature}
+
+// Provider supplies location headers from a trusted source other than the Cloudflare headers,
+// such as an upstream service. Providers are consulted in order by the middleware
+// (see WithProvider); the first that returns ok replaces the request headers.
+type Provider interface {
+	Provide(req *Request) (h Headers, ok bool)
+}
+
+// WithProvider consults p before the Cloudflare headers.
+func WithProvider(p Provider) Option {
+	return func(o *MiddlewareOptions) {
+		o.Providers = append(o.Providers, p)
+	}
+}
+
+// Transport is an http.RoundTripper that adds the geolocation of the incoming request,
+// as stored in the outgoing request's context by a middleware, to outgoing requests.
+// The values are sent as X-Geo-* headers signed with Key, so that downstream services
+// can trust them with a SignedHeaderProvider using the same key.
+//
+// Example:
+//
+//	client := &http.Client{Transport: geolocation.NewTransport(nil, key)}
+//	req, _ := http.NewRequestWithContext(r.Context(), "GET", "http://orders.internal/", nil)
+//	resp, err := client.Do(req)
+type Transport struct {
+	Base       http.RoundTripper // Underlying transport; http.DefaultTransport if nil
+	Key        []byte            // Shared HMAC key
+	ContextKey string            // Key the middleware stored the location under; DefaultContextKey if empty
+
+	now func() time.Time
+}
+
+// NewTransport returns a Transport signing with key on top of base.
+func NewTransport(base http.RoundTripper, key []byte) *Transport {
+	return &Transport{Base: base, Key: key}
+}
+
+// RoundTrip implements http.RoundTripper. The request is cloned before headers are added.
+// X-Geo-* headers already present on the request are always removed, so values that did not
+// come from the context are never forwarded.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	key := t.ContextKey
+	if key == "" {
+		key = DefaultContextKey
+	}
+
+	out := req.Clone(req.Context())
+	for _, name := range geoHeaders {
+		out.Header.Del(name)
+	}
+	if info := requestInfoFromContextKey(req.Context(), key); info != nil {
+		loc := info.Location()
+		lang := strings.Join(LanguageInfoFromHeaders(info.h).Supported, ",")
+		ts := strconv.FormatInt(t.time().Unix(), 10)
+		out.Header.Set(HeaderGeoCountry, loc.Country)
+		out.Header.Set(HeaderGeoIP, loc.IP)
+		if lang != "" {
+			out.Header.Set(HeaderGeoLang, lang)
+		}
+		out.Header.Set(HeaderGeoTimestamp, ts)
+		out.Header.Set(HeaderGeoSignature, signGeo(t.Key, loc.Country, loc.IP, lang, ts))
+	}
+	return base.RoundTrip(out)
+}
+
+func (t *Transport) time() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// SignedHeaderProvider is a Provider that trusts X-Geo-* headers only when they carry a
+// valid signature from one of Keys. The first key is the current one; the others allow
+// rotating keys without downtime.
+//
+// Example:
+//
+//	mw := geolocation.Middleware(geolocation.WithProvider(geolocation.NewSignedHeaderProvider(key)))
+type SignedHeaderProvider struct {
+	Keys   [][]byte      // Accepted HMAC keys
+	MaxAge time.Duration // Maximum signature age; DefaultSignatureMaxAge if zero
+
+	now func() time.Time
+}
+
+// NewSignedHeaderProvider returns a SignedHeaderProvider accepting signatures from any of keys.
+func NewSignedHeaderProvider(keys ...[]byte) *SignedHeaderProvider {
+	return &SignedHeaderProvider{Keys: keys}
+}
+
+// Verify checks the signature of the X-Geo-* headers in h.
+func (p *SignedHeaderProvider) Verify(h Headers) error {
+	sig, ts := h.Get(HeaderGeoSignature), h.Get(HeaderGeoTimestamp)
+	if sig == "" || ts == "" {
+		return ErrMissingSignature
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	maxAge := p.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultSignatureMaxAge
+	}
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	if age := now().Sub(time.Unix(unix, 0)); age > maxAge || age < -maxAge {
+		return ErrExpiredSignature
+	}
+	mac, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	country, ip, lang := h.Get(HeaderGeoCountry), h.Get(HeaderGeoIP), h.Get(HeaderGeoLang)
+	for _, key := range p.Keys {
+		if len(key) > 0 && hmac.Equal(mac, geoMAC(key, country, ip, lang, ts)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// Provide implements Provider. When the signature is valid, the X-Geo-* values replace
+// CF-IPCountry, CF-Connecting-IP and Accept-Language.
+func (p *SignedHeaderProvider) Provide(req *Request) (Headers, bool) {
+	if p.Verify(req.Header) != nil {
+		return nil, false
+	}
+	overrides := http.Header{}
+	overrides.Set("CF-IPCountry", req.Header.Get(HeaderGeoCountry))
+	overrides.Set("CF-Connecting-IP", req.Header.Get(HeaderGeoIP))
+	if lang := req.Header.Get(HeaderGeoLang); lang != "" {
+		overrides.Set("Accept-Language", lang)
+	}
+	return overlayHeaders{Headers: req.Header, overrides: overrides}, true
+}
+
+// signGeo returns the hex signature of the propagated values.
+func signGeo(key []byte, country, ip, lang, ts string) string {
+	return hex.EncodeToString(geoMAC(key, country, ip, lang, ts))
+}
+
+// geoMAC computes the HMAC-SHA256 of the propagated values. The values are newline-separated
+// and cannot contain newlines themselves, so the encoding is unambiguous.
+func geoMAC(key []byte, country, ip, lang, ts string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("v1\n" + country + "\n" + ip + "\n" + lang + "\n" + ts))
+	return mac.Sum(nil)
+}
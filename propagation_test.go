@@ -0,0 +1,161 @@
+package geolocation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// signedHeaders returns headers as sent by a Transport with key at time now.
+func signedHeaders(key []byte, country, ip, lang string, now time.Time) http.Header {
+	ts := strconv.FormatInt(now.Unix(), 10)
+	h := http.Header{}
+	h.Set(HeaderGeoCountry, country)
+	h.Set(HeaderGeoIP, ip)
+	h.Set(HeaderGeoLang, lang)
+	h.Set(HeaderGeoTimestamp, ts)
+	h.Set(HeaderGeoSignature, signGeo(key, country, ip, lang, ts))
+	return h
+}
+
+func TestTransport_Propagation(t *testing.T) {
+	key := []byte("shared-secret")
+
+	downstream := httptest.NewServer(Middleware(WithProvider(NewSignedHeaderProvider(key)))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := GeoInfoFromContext(r.Context())
+			if info == nil || info.CountryCode != "BG" || info.IP != "1.2.3.4" || info.PreferredLanguage != "bg-BG" {
+				t.Errorf("unexpected downstream GeoInfo: %+v", info)
+			}
+			if len(info.AllLanguages) != 2 || info.AllLanguages[1] != "en" {
+				t.Errorf("expected all languages to be propagated, got %v", info.AllLanguages)
+			}
+		})))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: NewTransport(nil, key)}
+	edge := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("downstream request failed: %v", err)
+		}
+		resp.Body.Close()
+		if req.Header.Get(HeaderGeoSignature) != "" {
+			t.Error("expected the original request not to be modified")
+		}
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-Connecting-IP", "1.2.3.4")
+	r.Header.Set("CF-IPCountry", "BG")
+	r.Header.Set("Accept-Language", "bg-BG,en;q=0.8")
+	edge.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestTransport_StripsHeadersWithoutContext(t *testing.T) {
+	var got http.Header
+	tr := NewTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+	}), []byte("k"))
+
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+	req.Header.Set(HeaderGeoCountry, "KP")
+	req.Header.Set(HeaderGeoSignature, "deadbeef")
+	if _, err := tr.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Get(HeaderGeoCountry) != "" || got.Get(HeaderGeoSignature) != "" {
+		t.Errorf("expected spoofed headers to be removed, got %v", got)
+	}
+}
+
+func TestTransport_ContextKey(t *testing.T) {
+	var got http.Header
+	tr := &Transport{
+		Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			got = r.Header
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+		}),
+		Key:        []byte("k"),
+		ContextKey: "geo",
+	}
+	h := Middleware(WithContextKey("geo"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", "http://example.com/", nil)
+		tr.RoundTrip(req)
+	}))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "FR")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got.Get(HeaderGeoCountry) != "FR" || got.Get(HeaderGeoLang) != "" {
+		t.Errorf("expected country from the custom key and no language, got %v", got)
+	}
+}
+
+func TestSignedHeaderProvider_Verify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	current, previous := []byte("current"), []byte("previous")
+	p := NewSignedHeaderProvider(current, previous)
+	p.now = func() time.Time { return now }
+
+	tampered := signedHeaders(current, "DE", "1.2.3.4", "de", now)
+	tampered.Set(HeaderGeoCountry, "US")
+	badHex := signedHeaders(current, "DE", "1.2.3.4", "de", now)
+	badHex.Set(HeaderGeoSignature, "zz")
+
+	cases := []struct {
+		name string
+		h    http.Header
+		want error
+	}{
+		{"current key", signedHeaders(current, "DE", "1.2.3.4", "de", now), nil},
+		{"rotated key", signedHeaders(previous, "DE", "1.2.3.4", "de", now), nil},
+		{"small clock skew", signedHeaders(current, "DE", "1.2.3.4", "", now.Add(time.Minute)), nil},
+		{"unknown key", signedHeaders([]byte("other"), "DE", "1.2.3.4", "de", now), ErrInvalidSignature},
+		{"tampered value", tampered, ErrInvalidSignature},
+		{"malformed signature", badHex, ErrInvalidSignature},
+		{"expired", signedHeaders(current, "DE", "1.2.3.4", "de", now.Add(-10*time.Minute)), ErrExpiredSignature},
+		{"from the future", signedHeaders(current, "DE", "1.2.3.4", "de", now.Add(10*time.Minute)), ErrExpiredSignature},
+		{"missing", http.Header{HeaderGeoCountry: {"DE"}}, ErrMissingSignature},
+	}
+	for _, c := range cases {
+		if err := p.Verify(c.h); !errors.Is(err, c.want) {
+			t.Errorf("%s: Verify() = %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestSignedHeaderProvider_Middleware(t *testing.T) {
+	key := []byte("k")
+	var got *Location
+	h := Middleware(WithProvider(NewSignedHeaderProvider(key)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	for k, v := range signedHeaders(key, "JP", "5.6.7.8", "ja", time.Now()) {
+		r.Header[k] = v
+	}
+	r.Header.Set("CF-IPCountry", "US")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got == nil || got.Country != "JP" || got.IP != "5.6.7.8" {
+		t.Errorf("expected signed headers to win, got %+v", got)
+	}
+
+	r.Header.Set(HeaderGeoCountry, "KP")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got == nil || got.Country != "US" {
+		t.Errorf("expected invalid signature to fall back to CF headers, got %+v", got)
+	}
+}
@@ -0,0 +1,203 @@
+package geolocation
+
+import (
+	"net/http"
+	"sync"
+)
+
+// GeoInfoOptions selects which parts of GeoInfo are computed for a request.
+// The location (IP and country) is always available; disabled parts are left empty.
+type GeoInfoOptions struct {
+	ClientInfo bool // Parse the User-Agent (browser, OS, device, in-app browser)
+	Language   bool // Parse the Accept-Language header
+	Resolution bool // Read screen and viewport headers and the screen cookie
+	Compliance bool // Evaluate privacy jurisdictions and the GPC and DNT headers
+}
+
+// DefaultGeoInfoOptions returns options that enable every part of GeoInfo.
+func DefaultGeoInfoOptions() GeoInfoOptions {
+	return GeoInfoOptions{ClientInfo: true, Language: true, Resolution: true, Compliance: true}
+}
+
+// RequestInfo lazily computes geolocation and client information for a single request.
+// Each part is computed at most once, on first access, so parts a handler never reads cost nothing.
+// It is safe for concurrent use.
+type RequestInfo struct {
+	h    Headers
+	opts GeoInfoOptions
+
+	locOnce    sync.Once
+	loc        *Location
+	clientOnce sync.Once
+	client     *ClientInfo
+	langOnce   sync.Once
+	lang       *LanguageInfo
+	resOnce    sync.Once
+	res        Resolution
+	geoOnce    sync.Once
+	geo        *GeoInfo
+	areaIndex  *AreaIndex
+	areasOnce  sync.Once
+	areas      []string
+	places     []Place
+	placeOnce  sync.Once
+	place      *RankedPlace
+	ipPrivacy  *IPPrivacy
+	compOnce   sync.Once
+	comp       *Compliance
+	langCookie string
+	langSigner *CookieSigner
+	config     *Config
+}
+
+// NewRequestInfo creates a RequestInfo for r. Nothing is parsed until a part is accessed.
+//
+// Example:
+//
+//	info := geolocation.NewRequestInfo(r, geolocation.GeoInfoOptions{Language: true})
+//	fmt.Println(info.Location().Country, info.LanguageInfo().Default)
+func NewRequestInfo(r *http.Request, opts GeoInfoOptions) *RequestInfo {
+	return NewRequestInfoFromHeaders(r.Header, opts)
+}
+
+// NewRequestInfoFromHeaders creates a RequestInfo backed by framework-neutral headers.
+// The headers must remain valid for as long as parts of the RequestInfo may still be computed.
+func NewRequestInfoFromHeaders(h Headers, opts GeoInfoOptions) *RequestInfo {
+	return &RequestInfo{h: h, opts: opts}
+}
+
+// Options returns the options the RequestInfo was created with.
+func (ri *RequestInfo) Options() GeoInfoOptions {
+	return ri.opts
+}
+
+// Location returns the geolocation extracted from the request.
+func (ri *RequestInfo) Location() *Location {
+	ri.locOnce.Do(func() {
+		ri.loc = locationFromHeaders(ri.h, ri.ipPrivacy)
+	})
+	return ri.loc
+}
+
+// ClientInfo returns the parsed User-Agent, or an empty ClientInfo if the part is disabled.
+func (ri *RequestInfo) ClientInfo() *ClientInfo {
+	ri.clientOnce.Do(func() {
+		if ri.opts.ClientInfo {
+			ri.client = ClientInfoFromHeaders(ri.h)
+		} else {
+			ri.client = &ClientInfo{}
+		}
+	})
+	return ri.client
+}
+
+// LanguageInfo returns the parsed Accept-Language header, or an empty LanguageInfo if the part is disabled.
+// With WithLanguageCookie, a valid language cookie comes first and becomes the default.
+func (ri *RequestInfo) LanguageInfo() *LanguageInfo {
+	ri.langOnce.Do(func() {
+		if ri.opts.Language {
+			ri.lang = LanguageInfoFromHeaders(ri.h)
+			if lang := ri.cookieLanguage(); lang != "" {
+				ri.lang = &LanguageInfo{Default: lang, Supported: append([]string{lang}, ri.lang.Supported...)}
+			}
+		} else {
+			ri.lang = &LanguageInfo{}
+		}
+	})
+	return ri.lang
+}
+
+// Config returns the site configuration the middleware was given with WithConfig, or nil.
+// It is the version current when the request started and does not change during the request.
+func (ri *RequestInfo) Config() *Config {
+	return ri.config
+}
+
+// SiteLanguage returns the best of available languages for the visitor's country, as with
+// GetLanguageForCountry, using the configuration from WithConfig and the visitor's browser
+// and cookie languages. It returns empty string without a configuration or a match.
+func (ri *RequestInfo) SiteLanguage(available ...string) string {
+	return languageForCountry(ri.config, ri.LanguageInfo(), ri.Location().Country, available)
+}
+
+// Resolution returns the screen resolution, or a zero Resolution if the part is disabled.
+func (ri *RequestInfo) Resolution() Resolution {
+	ri.resOnce.Do(func() {
+		if ri.opts.Resolution {
+			ri.res = ResolutionFromHeaders(ri.h)
+		}
+	})
+	return ri.res
+}
+
+// cookieLanguage returns the language stored in the cookie configured with WithLanguageCookie,
+// or empty string if there is none or it is invalid.
+func (ri *RequestInfo) cookieLanguage() string {
+	if ri.langCookie == "" {
+		return ""
+	}
+	var lang string
+	if ri.langSigner != nil {
+		lang = signedCookieFromHeaders(ri.h, ri.langSigner, ri.langCookie)
+	} else {
+		lang = cookieFromHeaders(ri.h, ri.langCookie)
+	}
+	if !IsLanguageTag(lang) {
+		return ""
+	}
+	return lang
+}
+
+// ShouldSetLanguage returns true if the cookie configured with WithLanguageCookie holds no valid
+// language: it is missing, not a language tag or, with a signer, tampered or expired.
+// It is false when WithLanguageCookie is not used. Framework adapters use it where net/http
+// handlers call ShouldSetLanguage.
+func (ri *RequestInfo) ShouldSetLanguage() bool {
+	return ri.langCookie != "" && ri.cookieLanguage() == ""
+}
+
+// Areas returns the IDs of the areas containing the request's coordinates,
+// when the middleware was configured with WithAreas.
+func (ri *RequestInfo) Areas() []string {
+	ri.areasOnce.Do(func() {
+		if ri.areaIndex != nil {
+			ri.areas = ri.areaIndex.Locate(ri.Location())
+		}
+	})
+	return ri.areas
+}
+
+// NearestPlace returns the place nearest to the visitor, when the middleware was configured with
+// WithNearestPlace. The visitor's coordinates are used if present, otherwise the centroid of their
+// country. Returns nil if there are no places or the visitor cannot be located.
+func (ri *RequestInfo) NearestPlace() *RankedPlace {
+	ri.placeOnce.Do(func() {
+		if len(ri.places) == 0 {
+			return
+		}
+		if c, ok := ri.Location().Coordinates(); ok {
+			ri.place = &Nearest(c, ri.places)[0]
+		}
+	})
+	return ri.place
+}
+
+// Compliance returns the privacy rules and signals that apply to the request,
+// or nil if the part is disabled.
+func (ri *RequestInfo) Compliance() *Compliance {
+	ri.compOnce.Do(func() {
+		if ri.opts.Compliance {
+			ri.comp = NewCompliance(ri.Location(), ri.h)
+		}
+	})
+	return ri.comp
+}
+
+// GeoInfo assembles all enabled parts into a GeoInfo.
+func (ri *RequestInfo) GeoInfo() *GeoInfo {
+	ri.geoOnce.Do(func() {
+		ri.geo = newGeoInfo(ri.Location(), ri.ClientInfo(), ri.LanguageInfo(), ri.Resolution())
+		ri.geo.Compliance = ri.Compliance()
+	})
+	return ri.geo
+}
@@ -0,0 +1,86 @@
+package geolocation
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRequestInfo_AllParts(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-Connecting-IP", "1.2.3.4")
+	r.Header.Set("CF-IPCountry", "BG")
+	r.Header.Set("User-Agent", benchUserAgent)
+	r.Header.Set("Accept-Language", "bg,en;q=0.8")
+	r.Header.Set("X-Screen-Width", "1920")
+
+	info := NewRequestInfo(r, DefaultGeoInfoOptions())
+	geo := info.GeoInfo()
+	want := GetGeoInfo(r)
+	if geo.CountryCode != want.CountryCode || geo.IP != want.IP || geo.Browser != want.Browser ||
+		geo.PreferredLanguage != want.PreferredLanguage || geo.Resolution != want.Resolution {
+		t.Errorf("expected %+v, got %+v", want, geo)
+	}
+	if info.GeoInfo() != geo {
+		t.Error("expected GeoInfo to be computed once")
+	}
+}
+
+func TestRequestInfo_DisabledParts(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("CF-IPCountry", "DE")
+	r.Header.Set("User-Agent", benchUserAgent)
+	r.Header.Set("Accept-Language", "de")
+	r.Header.Set("X-Screen-Width", "1920")
+
+	info := NewRequestInfo(r, GeoInfoOptions{Language: true})
+	geo := info.GeoInfo()
+	if geo.CountryCode != "DE" || geo.PreferredLanguage != "de" {
+		t.Errorf("expected location and language, got %+v", geo)
+	}
+	if geo.Browser != "" || geo.Resolution.Width != 0 {
+		t.Errorf("expected disabled parts to be empty, got %+v", geo)
+	}
+	if info.Options() != (GeoInfoOptions{Language: true}) {
+		t.Errorf("unexpected options: %+v", info.Options())
+	}
+}
+
+func TestRequestInfo_Lazy(t *testing.T) {
+	cache := EnableUACache(4)
+	defer DisableUACache()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", benchUserAgent)
+	info := NewRequestInfo(r, DefaultGeoInfoOptions())
+	info.Location()
+	info.LanguageInfo()
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 0 {
+		t.Errorf("expected User-Agent not to be parsed before ClientInfo is accessed, got %+v", stats)
+	}
+	info.ClientInfo()
+	info.ClientInfo()
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 1 {
+		t.Errorf("expected User-Agent to be parsed exactly once, got %+v", stats)
+	}
+}
+
+func TestRequestInfo_Concurrent(t *testing.T) {
+	r := SimulateRequest("FR", nil)
+	info := NewRequestInfo(r, DefaultGeoInfoOptions())
+	var wg sync.WaitGroup
+	results := make([]*GeoInfo, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = info.GeoInfo()
+		}(i)
+	}
+	wg.Wait()
+	for _, geo := range results {
+		if geo != results[0] || geo.CountryCode != "FR" {
+			t.Errorf("expected identical GeoInfo from all goroutines, got %+v", geo)
+		}
+	}
+}
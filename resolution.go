@@ -0,0 +1,163 @@
+package geolocation
+
+import (
+	_ "embed"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ScreenCookieName is the cookie written by the embedded screen beacon script.
+const ScreenCookieName = "geo_screen"
+
+// Sanity bounds for reported screen metrics. Values outside these ranges are ignored.
+const (
+	maxScreenPixels = 16384
+	minDPR          = 0.5
+	maxDPR          = 8
+)
+
+// clientHintsHeader lists the Client Hints read by GetResolution.
+const clientHintsHeader = "Sec-CH-DPR, Sec-CH-Viewport-Width, Sec-CH-Viewport-Height"
+
+//go:embed assets/screen.js
+var screenScript []byte
+
+// GetResolution retrieves screen and viewport information for the request.
+//
+// Screen size is read from X-Screen-Width/X-Screen-Height (if set by frontend JS), and DPR and
+// viewport from the Sec-CH-DPR, Sec-CH-Viewport-Width and Sec-CH-Viewport-Height Client Hints.
+// Any value still missing is taken from the ScreenCookieName cookie set by the embedded beacon
+// (see ScreenScriptHandler). Values outside sane bounds are ignored.
+func GetResolution(r *http.Request) Resolution {
+	return ResolutionFromHeaders(r.Header)
+}
+
+// ResolutionFromHeaders retrieves screen and viewport information from headers. See GetResolution.
+func ResolutionFromHeaders(h Headers) Resolution {
+	res := Resolution{
+		Width:          parsePixels(h.Get("X-Screen-Width")),
+		Height:         parsePixels(h.Get("X-Screen-Height")),
+		DPR:            parseDPR(h.Get("Sec-CH-DPR")),
+		ViewportWidth:  parsePixels(h.Get("Sec-CH-Viewport-Width")),
+		ViewportHeight: parsePixels(h.Get("Sec-CH-Viewport-Height")),
+	}
+
+	if cookie := cookieFromHeaders(h, ScreenCookieName); cookie != "" {
+		if values, err := url.ParseQuery(cookie); err == nil {
+			if res.Width == 0 {
+				res.Width = parsePixels(values.Get("w"))
+			}
+			if res.Height == 0 {
+				res.Height = parsePixels(values.Get("h"))
+			}
+			if res.DPR == 0 {
+				res.DPR = parseDPR(values.Get("dpr"))
+			}
+			if res.ViewportWidth == 0 {
+				res.ViewportWidth = parsePixels(values.Get("vw"))
+			}
+			if res.ViewportHeight == 0 {
+				res.ViewportHeight = parsePixels(values.Get("vh"))
+			}
+		}
+	}
+
+	res.Orientation = orientation(res)
+	return res
+}
+
+// Breakpoint returns the responsive breakpoint bucket (xs, sm, md, lg, xl) for the
+// viewport width, falling back to the screen width. Returns empty string if neither is known.
+func (res Resolution) Breakpoint() string {
+	width := res.ViewportWidth
+	if width == 0 {
+		width = res.Width
+	}
+	return BreakpointFor(width)
+}
+
+// BreakpointFor returns the responsive breakpoint bucket for a width in CSS pixels:
+// xs (<576), sm (<768), md (<992), lg (<1200) or xl. Returns empty string for non-positive widths.
+func BreakpointFor(width int) string {
+	switch {
+	case width <= 0:
+		return ""
+	case width < 576:
+		return "xs"
+	case width < 768:
+		return "sm"
+	case width < 992:
+		return "md"
+	case width < 1200:
+		return "lg"
+	default:
+		return "xl"
+	}
+}
+
+// RequestClientHints asks the browser to send the screen-related Client Hints
+// on subsequent requests by setting the Accept-CH response header.
+func RequestClientHints(w http.ResponseWriter) {
+	w.Header().Set("Accept-CH", clientHintsHeader)
+}
+
+// ScreenScript returns the embedded JavaScript beacon that stores screen metrics
+// in the ScreenCookieName cookie. It can be inlined into a <script> tag.
+func ScreenScript() string {
+	return string(screenScript)
+}
+
+// ScreenScriptHandler serves the embedded screen beacon script.
+//
+// Example:
+//
+//	mux.Handle("/geo/screen.js", geolocation.ScreenScriptHandler())
+//	// <script src="/geo/screen.js" async></script>
+func ScreenScriptHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(screenScript)
+	})
+}
+
+// orientation derives the orientation from the viewport, or the screen if the viewport is unknown.
+func orientation(res Resolution) string {
+	width, height := res.ViewportWidth, res.ViewportHeight
+	if width == 0 || height == 0 {
+		width, height = res.Width, res.Height
+	}
+	switch {
+	case width == 0 || height == 0:
+		return ""
+	case width >= height:
+		return "landscape"
+	default:
+		return "portrait"
+	}
+}
+
+// parsePixels parses a pixel dimension, returning 0 if invalid or out of bounds.
+func parsePixels(s string) int {
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || n > maxScreenPixels {
+		return 0
+	}
+	return n
+}
+
+// parseDPR parses a device pixel ratio, returning 0 if invalid or out of bounds.
+func parseDPR(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(f >= minDPR && f <= maxDPR) { // also rejects NaN
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,97 @@
+package geolocation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetResolution_CustomHeaders(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Screen-Width", "1920")
+	r.Header.Set("X-Screen-Height", "1080")
+	res := GetResolution(r)
+	if res.Width != 1920 || res.Height != 1080 || res.Orientation != "landscape" {
+		t.Errorf("unexpected resolution: %+v", res)
+	}
+}
+
+func TestGetResolution_ClientHints(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Sec-CH-DPR", "2.5")
+	r.Header.Set("Sec-CH-Viewport-Width", "412")
+	r.Header.Set("Sec-CH-Viewport-Height", "915")
+	res := GetResolution(r)
+	if res.DPR != 2.5 || res.ViewportWidth != 412 || res.ViewportHeight != 915 {
+		t.Errorf("unexpected client hints: %+v", res)
+	}
+	if res.Orientation != "portrait" || res.Breakpoint() != "xs" {
+		t.Errorf("unexpected orientation/breakpoint: %s/%s", res.Orientation, res.Breakpoint())
+	}
+}
+
+func TestGetResolution_Cookie(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: ScreenCookieName, Value: "w=2560&h=1440&dpr=2&vw=1280&vh=720"})
+	res := GetResolution(r)
+	want := Resolution{Width: 2560, Height: 1440, DPR: 2, ViewportWidth: 1280, ViewportHeight: 720, Orientation: "landscape"}
+	if res != want {
+		t.Errorf("expected %+v, got %+v", want, res)
+	}
+}
+
+func TestGetResolution_HeadersTakePrecedenceOverCookie(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Sec-CH-Viewport-Width", "800")
+	r.AddCookie(&http.Cookie{Name: ScreenCookieName, Value: "w=2560&h=1440&vw=1280&vh=720"})
+	res := GetResolution(r)
+	if res.ViewportWidth != 800 || res.ViewportHeight != 720 || res.Width != 2560 {
+		t.Errorf("unexpected merged resolution: %+v", res)
+	}
+}
+
+func TestGetResolution_OutOfBounds(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Screen-Width", "-5")
+	r.Header.Set("X-Screen-Height", "999999")
+	r.Header.Set("Sec-CH-DPR", "NaN")
+	r.Header.Set("Sec-CH-Viewport-Width", "abc")
+	r.AddCookie(&http.Cookie{Name: ScreenCookieName, Value: "dpr=100&vh=0"})
+	res := GetResolution(r)
+	if res != (Resolution{}) {
+		t.Errorf("expected all values to be rejected, got %+v", res)
+	}
+}
+
+func TestBreakpointFor(t *testing.T) {
+	cases := map[int]string{0: "", 320: "xs", 576: "sm", 768: "md", 992: "lg", 1199: "lg", 1200: "xl", 3840: "xl"}
+	for width, want := range cases {
+		if got := BreakpointFor(width); got != want {
+			t.Errorf("BreakpointFor(%d) = %q, want %q", width, got, want)
+		}
+	}
+	if got := (Resolution{Width: 1024}).Breakpoint(); got != "lg" {
+		t.Errorf("expected screen width fallback 'lg', got %q", got)
+	}
+}
+
+func TestRequestClientHints(t *testing.T) {
+	w := httptest.NewRecorder()
+	RequestClientHints(w)
+	if got := w.Header().Get("Accept-CH"); !strings.Contains(got, "Sec-CH-DPR") || !strings.Contains(got, "Sec-CH-Viewport-Width") {
+		t.Errorf("unexpected Accept-CH header: %q", got)
+	}
+}
+
+func TestScreenScriptHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	ScreenScriptHandler().ServeHTTP(w, httptest.NewRequest("GET", "/geo/screen.js", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Errorf("unexpected content type: %q", ct)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, ScreenCookieName+"=") || body != ScreenScript() {
+		t.Error("expected handler to serve the embedded beacon script")
+	}
+}
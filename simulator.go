@@ -133,6 +133,10 @@ func FakeCloudflareHeaders(countryCode string, options *SimulationOptions) map[s
 		"X-Forwarded-For":  fakeIP,
 	}
 
+	if exists {
+		headers["CF-Timezone"] = data.Timezone
+	}
+
 	if options != nil && options.ServerName != "" {
 		headers["Server-Name"] = options.ServerName
 		headers["HTTP_HOST"] = options.ServerName
@@ -0,0 +1,226 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Default request parameters read by LanguageSwitcher.
+const (
+	DefaultLanguageParam = "lang"       // The chosen language
+	DefaultReturnParam   = "return_to"  // Where to redirect afterwards
+	DefaultCSRFParam     = "csrf_token" // The CSRF token of POST requests
+	CSRFHeader           = "X-CSRF-Token"
+)
+
+// LanguageSwitcher is a ready-made "choose your language" endpoint. It validates the requested
+// language against the site languages, stores it in the Config.CookieName cookie and redirects back.
+//
+// The redirect target is the return_to parameter, or else the Referer. Relative paths are always
+// accepted; absolute URLs only when they point to the request host or one of AllowedHosts, so the
+// endpoint cannot be used as an open redirect. Clients sending Accept: application/json get a JSON
+// response instead of a redirect.
+//
+// Create it with NewLanguageSwitcher and mount it on any method; adapters provide equivalents for
+// Gin, Echo and Fiber.
+//
+// Example:
+//
+//	switcher, err := geolocation.NewLanguageSwitcher(cfg, "en", "de", "fr")
+//	switcher.AllowedHosts = []string{"example.com", "*.example.com"}
+//	switcher.Cookie = &http.Cookie{MaxAge: 365 * 24 * 3600, SameSite: http.SameSiteLaxMode}
+//	mux.Handle("/language", switcher) // GET /language?lang=de&return_to=/products
+type LanguageSwitcher struct {
+	CookieName   string                                // Name of the language cookie, from Config.CookieName
+	Cookie       *http.Cookie                          // Cookie options passed to SetCookie, e.g. MaxAge
+	Signer       *CookieSigner                         // Signs the cookie when set; pair with WithLanguageCookie
+	AllowedHosts []string                              // Hosts absolute return URLs may point to besides the request host; "*.example.com" matches subdomains
+	Fallback     string                                // Redirect target without a valid return URL; defaults to "/"
+	PostOnly     bool                                  // Reject GET requests, e.g. when the switch is a form
+	CSRF         func(req *Request, token string) bool // Validates the CSRF token of POST requests; nil accepts all
+
+	languages map[string]string // Lowercase tag -> configured spelling
+}
+
+// LanguageSwitch is the JSON body sent to clients that accept JSON.
+type LanguageSwitch struct {
+	Language string `json:"language,omitempty"` // The language that was set
+	Redirect string `json:"redirect,omitempty"` // Where a browser would have been redirected
+	Error    string `json:"error,omitempty"`    // Why the request was rejected
+}
+
+// Errors reported by LanguageSwitcher.
+var (
+	ErrUnsupportedLanguage = errors.New("geolocation: unsupported language")
+	ErrInvalidCSRFToken    = errors.New("geolocation: invalid CSRF token")
+)
+
+// NewLanguageSwitcher returns a LanguageSwitcher for the cookie named in cfg.
+// Visitors may choose any of languages or, without them, any language in cfg:
+// the default language and every language in the country map. Matching is case-insensitive.
+func NewLanguageSwitcher(cfg *Config, languages ...string) (*LanguageSwitcher, error) {
+	if cfg == nil || cfg.view().CookieName == "" {
+		return nil, errors.New("geolocation: language switcher requires Config.CookieName")
+	}
+	if len(languages) == 0 {
+		languages = cfg.Languages()
+	}
+	s := &LanguageSwitcher{CookieName: cfg.view().CookieName, languages: map[string]string{}}
+	for _, lang := range languages {
+		if lang == "" {
+			continue
+		}
+		if !IsLanguageTag(lang) {
+			return nil, fmt.Errorf("geolocation: language switcher: invalid language %q", lang)
+		}
+		s.languages[strings.ToLower(lang)] = lang
+	}
+	if len(s.languages) == 0 {
+		return nil, errors.New("geolocation: language switcher has no languages")
+	}
+	return s, nil
+}
+
+// Switch handles a language switch request. params holds the query and form values.
+// It returns the status code, headers (including Set-Cookie) and body of the response;
+// adapters use it to write the response in their framework.
+func (s *LanguageSwitcher) Switch(req *Request, params url.Values) (int, http.Header, []byte) {
+	header := http.Header{}
+	asJSON := acceptsJSON(req.Header)
+	fail := func(status int, err error) (int, http.Header, []byte) {
+		if asJSON {
+			return writeSwitchJSON(status, header, LanguageSwitch{Error: err.Error()})
+		}
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		return status, header, []byte(err.Error())
+	}
+
+	allowed := []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	if s.PostOnly {
+		allowed = []string{http.MethodPost}
+	}
+	if !slices.Contains(allowed, req.Method) {
+		header.Set("Allow", strings.Join(allowed, ", "))
+		return fail(http.StatusMethodNotAllowed, errors.New(http.StatusText(http.StatusMethodNotAllowed)))
+	}
+	if req.Method == http.MethodPost && s.CSRF != nil {
+		token := params.Get(DefaultCSRFParam)
+		if token == "" {
+			token = req.Header.Get(CSRFHeader)
+		}
+		if !s.CSRF(req, token) {
+			return fail(http.StatusForbidden, ErrInvalidCSRFToken)
+		}
+	}
+
+	lang, ok := s.languages[strings.ToLower(params.Get(DefaultLanguageParam))]
+	if !ok {
+		return fail(http.StatusBadRequest, ErrUnsupportedLanguage)
+	}
+	w := headerWriter(header)
+	if s.Signer != nil {
+		if err := SetSignedCookie(w, s.Signer, s.CookieName, lang, s.Cookie); err != nil {
+			return fail(http.StatusInternalServerError, err)
+		}
+	} else {
+		SetCookie(w, s.CookieName, lang, s.Cookie)
+	}
+
+	target := s.returnURL(req, params.Get(DefaultReturnParam))
+	if asJSON {
+		return writeSwitchJSON(http.StatusOK, header, LanguageSwitch{Language: lang, Redirect: target})
+	}
+	header.Set("Location", target)
+	if req.Method == http.MethodPost {
+		return http.StatusSeeOther, header, nil
+	}
+	return http.StatusFound, header, nil
+}
+
+// ServeHTTP handles a language switch request with parameters from the query or a form body.
+func (s *LanguageSwitcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var status int
+	var header http.Header
+	var body []byte
+	if err := r.ParseForm(); err != nil {
+		status, header = http.StatusBadRequest, http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+		body = []byte(http.StatusText(status))
+	} else {
+		status, header, body = s.Switch(NewRequest(r), r.Form)
+	}
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// returnURL picks the redirect target: return_to, then the Referer, then Fallback.
+func (s *LanguageSwitcher) returnURL(req *Request, returnTo string) string {
+	for _, target := range []string{returnTo, req.Header.Get("Referer")} {
+		if s.safeRedirect(req, target) {
+			return target
+		}
+	}
+	if s.Fallback != "" {
+		return s.Fallback
+	}
+	return "/"
+}
+
+// safeRedirect reports whether target is a local path or an http(s) URL on an allowed host.
+func (s *LanguageSwitcher) safeRedirect(req *Request, target string) bool {
+	if target == "" || strings.ContainsAny(target, "\\\r\n\t") {
+		return false
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		// Relative paths only; "//host" is protocol-relative and goes off-site.
+		return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, req.Host) {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range s.AllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == host || allowed == strings.ToLower(u.Host) {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsJSON reports whether the client asked for a JSON response.
+func acceptsJSON(h Headers) bool {
+	return strings.Contains(strings.ToLower(h.Get("Accept")), "application/json")
+}
+
+func writeSwitchJSON(status int, header http.Header, v LanguageSwitch) (int, http.Header, []byte) {
+	body, _ := json.Marshal(v)
+	header.Set("Content-Type", "application/json")
+	return status, header, body
+}
+
+// headerWriter collects the headers written by SetCookie.
+type headerWriter http.Header
+
+func (w headerWriter) Header() http.Header       { return http.Header(w) }
+func (headerWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (headerWriter) WriteHeader(int)             {}
@@ -0,0 +1,192 @@
+package geolocation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestSwitcher(t *testing.T, languages ...string) *LanguageSwitcher {
+	t.Helper()
+	cfg := &Config{
+		DefaultLanguage:      "en",
+		CookieName:           "site_lang",
+		CountryToLanguageMap: map[string][]string{"CH": {"de", "fr", "it"}, "BR": {"pt-BR"}},
+	}
+	s, err := NewLanguageSwitcher(cfg, languages...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewLanguageSwitcher(t *testing.T) {
+	if _, err := NewLanguageSwitcher(nil); err == nil {
+		t.Error("expected error for nil config")
+	}
+	if _, err := NewLanguageSwitcher(&Config{DefaultLanguage: "en"}); err == nil {
+		t.Error("expected error without cookie name")
+	}
+	if _, err := NewLanguageSwitcher(&Config{CookieName: "lang"}); err == nil {
+		t.Error("expected error without languages")
+	}
+	if _, err := NewLanguageSwitcher(&Config{CookieName: "lang"}, "en", "<de>"); err == nil {
+		t.Error("expected error for invalid language")
+	}
+
+	s := newTestSwitcher(t)
+	for _, lang := range []string{"en", "de", "fr", "it", "pt-BR"} {
+		if s.languages[strings.ToLower(lang)] != lang {
+			t.Errorf("expected %s from config, got %v", lang, s.languages)
+		}
+	}
+}
+
+func TestLanguageSwitcher_Redirect(t *testing.T) {
+	s := newTestSwitcher(t)
+	s.AllowedHosts = []string{"shop.example.com", "*.example.org"}
+	s.Cookie = &http.Cookie{MaxAge: 3600, HttpOnly: true}
+
+	tests := []struct {
+		name, query, referer string
+		status               int
+		location, cookie     string
+	}{
+		{"return_to path", "lang=de&return_to=/products?id=1", "", 302, "/products?id=1", "site_lang=de"},
+		{"case-insensitive", "lang=PT-br", "", 302, "/", "site_lang=pt-BR"},
+		{"referer on request host", "lang=fr", "http://example.com/about", 302, "http://example.com/about", "site_lang=fr"},
+		{"referer on allowed host", "lang=fr", "https://shop.example.com/cart", 302, "https://shop.example.com/cart", "site_lang=fr"},
+		{"wildcard host", "lang=it&return_to=https://a.b.example.org/x", "", 302, "https://a.b.example.org/x", "site_lang=it"},
+		{"wildcard excludes apex", "lang=it&return_to=https://example.org/x", "", 302, "/", "site_lang=it"},
+		{"foreign return_to falls back to referer", "lang=de&return_to=https://evil.com/", "/home", 302, "/home", "site_lang=de"},
+		{"protocol-relative", "lang=de&return_to=//evil.com/", "", 302, "/", "site_lang=de"},
+		{"backslash", "lang=de&return_to=/\\evil.com", "", 302, "/", "site_lang=de"},
+		{"javascript scheme", "lang=de&return_to=javascript:alert(1)", "", 302, "/", "site_lang=de"},
+		{"userinfo", "lang=de&return_to=http://example.com@evil.com/", "", 302, "/", "site_lang=de"},
+		{"lookalike host", "lang=de", "https://shop.example.com.evil.com/", 302, "/", "site_lang=de"},
+		{"unsupported language", "lang=es&return_to=/", "", 400, "", ""},
+		{"invalid language", "lang=%3Cscript%3E", "", 400, "", ""},
+		{"missing language", "", "", 400, "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/language?"+tt.query, nil)
+		if tt.referer != "" {
+			r.Header.Set("Referer", tt.referer)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if got := w.Header().Get("Location"); got != tt.location {
+			t.Errorf("%s: Location = %q, want %q", tt.name, got, tt.location)
+		}
+		cookie := w.Header().Get("Set-Cookie")
+		if tt.cookie == "" && cookie != "" || !strings.HasPrefix(cookie, tt.cookie) {
+			t.Errorf("%s: Set-Cookie = %q, want %q", tt.name, cookie, tt.cookie)
+		}
+		if tt.cookie != "" && (!strings.Contains(cookie, "Max-Age=3600") || !strings.Contains(cookie, "HttpOnly")) {
+			t.Errorf("%s: expected cookie options, got %q", tt.name, cookie)
+		}
+	}
+
+	s.Fallback = "/start"
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/language?lang=de", nil))
+	if w.Header().Get("Location") != "/start" {
+		t.Errorf("expected fallback, got %q", w.Header().Get("Location"))
+	}
+}
+
+func TestLanguageSwitcher_Post(t *testing.T) {
+	s := newTestSwitcher(t, "en", "de")
+	s.PostOnly = true
+	s.CSRF = func(req *Request, token string) bool { return token == "secret" }
+
+	post := func(form url.Values, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/language", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	w := post(url.Values{"lang": {"de"}, "csrf_token": {"secret"}, "return_to": {"/page"}}, nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/page" || !strings.HasPrefix(w.Header().Get("Set-Cookie"), "site_lang=de") {
+		t.Errorf("unexpected POST response %d %v", w.Code, w.Header())
+	}
+	w = post(url.Values{"lang": {"de"}}, map[string]string{CSRFHeader: "secret"})
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("expected CSRF token from header, got %d", w.Code)
+	}
+	w = post(url.Values{"lang": {"de"}, "csrf_token": {"wrong"}}, nil)
+	if w.Code != http.StatusForbidden || w.Header().Get("Set-Cookie") != "" {
+		t.Errorf("expected 403 for invalid CSRF token, got %d %v", w.Code, w.Header())
+	}
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/language?lang=de", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "POST" {
+		t.Errorf("expected 405 for GET, got %d %v", w.Code, w.Header())
+	}
+	s.PostOnly = false
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("DELETE", "/language?lang=de", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD, POST" {
+		t.Errorf("expected 405 for DELETE, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestLanguageSwitcher_JSON(t *testing.T) {
+	s := newTestSwitcher(t)
+	r := httptest.NewRequest("GET", "/language?lang=fr&return_to=/fr", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	var body LanguageSwitch
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 200 || w.Header().Get("Location") != "" || body.Language != "fr" || body.Redirect != "/fr" {
+		t.Errorf("unexpected JSON response %d %+v", w.Code, body)
+	}
+	if !strings.HasPrefix(w.Header().Get("Set-Cookie"), "site_lang=fr") {
+		t.Errorf("expected cookie, got %q", w.Header().Get("Set-Cookie"))
+	}
+
+	r = httptest.NewRequest("GET", "/language?lang=xx", nil)
+	r.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != 400 || !strings.Contains(w.Body.String(), `"error":"geolocation: unsupported language"`) {
+		t.Errorf("unexpected JSON error %d %s", w.Code, w.Body)
+	}
+}
+
+func TestLanguageSwitcher_Signed(t *testing.T) {
+	signer := NewCookieSigner([]byte("key"))
+	s := newTestSwitcher(t)
+	s.Signer = signer
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/language?lang=de", nil))
+	r := cookieRequest(w)
+	if got := GetSignedCookie(r, signer, "site_lang"); got != "de" {
+		t.Errorf("expected signed cookie, got %q", got)
+	}
+
+	var info *GeoInfo
+	Middleware(WithLanguageCookie("site_lang", signer))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info = GeoInfoFromContext(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if info.PreferredLanguage != "de" {
+		t.Errorf("expected middleware to read the switched language, got %q", info.PreferredLanguage)
+	}
+}
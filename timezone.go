@@ -0,0 +1,194 @@
+package geolocation
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Fallback when the system has no zoneinfo, e.g. scratch containers
+)
+
+// ErrUnknownTimeZone is returned when a location has no time zone.
+var ErrUnknownTimeZone = errors.New("geolocation: unknown time zone")
+
+// timeZones caches loaded zones; time.LoadLocation parses zoneinfo on every call.
+var timeZones sync.Map // name -> *time.Location
+
+// maxTimeZoneFailures bounds the cache of names that failed to load. Names come from request
+// headers, so the cache is cleared rather than grown when it is full.
+const maxTimeZoneFailures = 1024
+
+// timeZoneFailures caches names that failed to load, so that a bad header does not
+// hit the file system on every request.
+var timeZoneFailures struct {
+	sync.Mutex
+	errs map[string]error
+}
+
+// maxTimeZoneName is longer than any IANA zone name, the longest being about 30 characters.
+const maxTimeZoneName = 64
+
+// LoadTimeZone returns the IANA time zone with the given name, e.g. Europe/Sofia.
+// Zones are read from the system and fall back to the tzdata embedded in the binary.
+// Names that cannot be zone names return ErrUnknownTimeZone without a lookup.
+// Loaded zones and recent failures are cached.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if !validTimeZoneName(name) {
+		return nil, ErrUnknownTimeZone
+	}
+	if tz, ok := timeZones.Load(name); ok {
+		return tz.(*time.Location), nil
+	}
+	timeZoneFailures.Lock()
+	err, failed := timeZoneFailures.errs[name]
+	timeZoneFailures.Unlock()
+	if failed {
+		return nil, err
+	}
+	tz, err := time.LoadLocation(name)
+	if err != nil {
+		timeZoneFailures.Lock()
+		if len(timeZoneFailures.errs) >= maxTimeZoneFailures {
+			clear(timeZoneFailures.errs)
+		}
+		if timeZoneFailures.errs == nil {
+			timeZoneFailures.errs = map[string]error{}
+		}
+		timeZoneFailures.errs[name] = err
+		timeZoneFailures.Unlock()
+		return nil, err
+	}
+	timeZones.Store(name, tz)
+	return tz, nil
+}
+
+// validTimeZoneName reports whether name looks like an IANA zone name, e.g. America/New_York,
+// Etc/GMT+5 or UTC: slash-separated parts of letters, digits, '_', '-' and '+'.
+func validTimeZoneName(name string) bool {
+	if name == "" || len(name) > maxTimeZoneName {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			switch {
+			case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-', r == '+':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TimeZoneOf returns the primary IANA time zone of a country, or empty string if the country is unknown.
+// Countries spanning several zones report the zone of their capital or most populous area.
+func TimeZoneOf(country string) string {
+	c, _ := LookupCountry(country)
+	return c.TimeZone
+}
+
+// resolveTimeZone returns header if it names a valid zone, otherwise the country's primary zone.
+func resolveTimeZone(header, country string) string {
+	if header == "Local" {
+		return TimeZoneOf(country) // the server's zone, not the visitor's
+	}
+	if _, err := LoadTimeZone(header); err == nil {
+		return header
+	}
+	return TimeZoneOf(country)
+}
+
+// TimeLocation returns the visitor's time zone.
+func (l *Location) TimeLocation() (*time.Location, error) {
+	return LoadTimeZone(l.TimeZone)
+}
+
+// LocalTime returns the current time in the visitor's time zone.
+//
+// Example:
+//
+//	if now, err := geolocation.FromRequest(r).LocalTime(); err == nil {
+//		fmt.Println(now.Format(time.Kitchen))
+//	}
+func (l *Location) LocalTime() (time.Time, error) {
+	tz, err := l.TimeLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(tz), nil
+}
+
+// UTCOffset returns the visitor's current offset from UTC, taking daylight saving time into account.
+func (l *Location) UTCOffset() (time.Duration, error) {
+	now, err := l.LocalTime()
+	if err != nil {
+		return 0, err
+	}
+	_, offset := now.Zone()
+	return time.Duration(offset) * time.Second, nil
+}
+
+// InBusinessHours reports whether it is currently within schedule in the visitor's time zone.
+func (l *Location) InBusinessHours(schedule Schedule) (bool, error) {
+	now, err := l.LocalTime()
+	if err != nil {
+		return false, err
+	}
+	return schedule.Contains(now), nil
+}
+
+// BusinessHours is a daily opening window. Open and Close are offsets from midnight;
+// a Close at or before Open spans midnight, e.g. 22:00 to 06:00.
+type BusinessHours struct {
+	Days  []time.Weekday // Days the window opens on; empty means Monday to Friday
+	Open  time.Duration  // e.g. 9 * time.Hour
+	Close time.Duration  // e.g. 17*time.Hour + 30*time.Minute
+}
+
+// Schedule is a set of opening windows. A time is within the schedule if any window contains it.
+type Schedule []BusinessHours
+
+// DefaultSchedule is 09:00 to 17:00, Monday to Friday.
+var DefaultSchedule = Schedule{{Open: 9 * time.Hour, Close: 17 * time.Hour}}
+
+var weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// Contains reports whether t, in its own location, falls within the window.
+// For windows spanning midnight, the early-morning part belongs to the previous day's window.
+func (b BusinessHours) Contains(t time.Time) bool {
+	days := b.Days
+	if len(days) == 0 {
+		days = weekdays
+	}
+	since := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	if b.Open < b.Close {
+		return slices.Contains(days, t.Weekday()) && since >= b.Open && since < b.Close
+	}
+	if since >= b.Open {
+		return slices.Contains(days, t.Weekday())
+	}
+	return since < b.Close && slices.Contains(days, (t.Weekday()+6)%7)
+}
+
+// Contains reports whether t, in its own location, falls within any window of the schedule.
+//
+// Example:
+//
+//	support := geolocation.Schedule{
+//		{Open: 8 * time.Hour, Close: 20 * time.Hour},
+//		{Days: []time.Weekday{time.Saturday}, Open: 10 * time.Hour, Close: 14 * time.Hour},
+//	}
+//	open := support.Contains(time.Now().In(tz))
+func (s Schedule) Contains(t time.Time) bool {
+	for _, b := range s {
+		if b.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
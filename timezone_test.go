@@ -0,0 +1,194 @@
+package geolocation
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTimeZone(t *testing.T) {
+	tz, err := LoadTimeZone("Europe/Sofia")
+	if err != nil || tz.String() != "Europe/Sofia" {
+		t.Fatalf("LoadTimeZone: %v, %v", tz, err)
+	}
+	if again, _ := LoadTimeZone("Europe/Sofia"); again != tz {
+		t.Error("expected loaded zones to be cached")
+	}
+	if _, err := LoadTimeZone(""); !errors.Is(err, ErrUnknownTimeZone) {
+		t.Errorf("expected ErrUnknownTimeZone, got %v", err)
+	}
+	if _, err := LoadTimeZone("Mars/Olympus_Mons"); err == nil {
+		t.Error("expected error for unknown zone")
+	}
+}
+
+func TestLoadTimeZone_InvalidNames(t *testing.T) {
+	for _, name := range []string{
+		"Europe/Sofia ",
+		"/etc/passwd",
+		"../zoneinfo/UTC",
+		"Europe//Sofia",
+		"Europe/Sofia/",
+		"Europe\\Sofia",
+		strings.Repeat("A", maxTimeZoneName+1),
+	} {
+		if _, err := LoadTimeZone(name); !errors.Is(err, ErrUnknownTimeZone) {
+			t.Errorf("LoadTimeZone(%q): expected ErrUnknownTimeZone, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"UTC", "Etc/GMT+5", "America/Argentina/Buenos_Aires", "America/Port-au-Prince"} {
+		if _, err := LoadTimeZone(name); err != nil {
+			t.Errorf("LoadTimeZone(%q): %v", name, err)
+		}
+	}
+}
+
+func TestLoadTimeZone_CachesFailures(t *testing.T) {
+	_, err := LoadTimeZone("Mars/Valles_Marineris")
+	if err == nil {
+		t.Fatal("expected error for unknown zone")
+	}
+	timeZoneFailures.Lock()
+	cached := timeZoneFailures.errs["Mars/Valles_Marineris"]
+	timeZoneFailures.Unlock()
+	if cached != err {
+		t.Errorf("expected failure to be cached, got %v", cached)
+	}
+	if _, again := LoadTimeZone("Mars/Valles_Marineris"); again != err {
+		t.Errorf("expected cached error, got %v", again)
+	}
+
+	for i := range maxTimeZoneFailures + 10 {
+		LoadTimeZone(fmt.Sprintf("Mars/Crater_%d", i))
+	}
+	timeZoneFailures.Lock()
+	n := len(timeZoneFailures.errs)
+	timeZoneFailures.Unlock()
+	if n > maxTimeZoneFailures {
+		t.Errorf("failure cache grew to %d entries", n)
+	}
+}
+
+func TestCountryTimeZones(t *testing.T) {
+	for _, code := range Countries() {
+		name := TimeZoneOf(code)
+		if _, err := LoadTimeZone(name); err != nil {
+			t.Errorf("country %s has invalid time zone %q: %v", code, name, err)
+		}
+	}
+	if got := TimeZoneOf("ch"); got != "Europe/Zurich" {
+		t.Errorf("TimeZoneOf(ch) = %q", got)
+	}
+	if got := TimeZoneOf("XX"); got != "" {
+		t.Errorf("expected no zone for unknown country, got %q", got)
+	}
+}
+
+func TestFromHeaders_TimeZone(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"header", map[string]string{"CF-IPCountry": "US", "CF-Timezone": "America/Los_Angeles"}, "America/Los_Angeles"},
+		{"country fallback", map[string]string{"CF-IPCountry": "BG"}, "Europe/Sofia"},
+		{"invalid header", map[string]string{"CF-IPCountry": "JP", "CF-Timezone": "Not/AZone"}, "Asia/Tokyo"},
+		{"server zone", map[string]string{"CF-IPCountry": "DE", "CF-Timezone": "Local"}, "Europe/Berlin"},
+		{"nothing", map[string]string{}, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		if got := FromRequest(r).TimeZone; got != tt.want {
+			t.Errorf("%s: TimeZone = %q, want %q", tt.name, got, tt.want)
+		}
+		if got := GetGeoInfo(r).TimeZone; got != tt.want {
+			t.Errorf("%s: GeoInfo.TimeZone = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocation_LocalTime(t *testing.T) {
+	loc := &Location{TimeZone: "Asia/Kolkata"}
+	now, err := loc.LocalTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now.Location().String() != "Asia/Kolkata" {
+		t.Errorf("unexpected location %v", now.Location())
+	}
+	if offset, err := loc.UTCOffset(); err != nil || offset != 5*time.Hour+30*time.Minute {
+		t.Errorf("UTCOffset = %v, %v", offset, err)
+	}
+
+	if _, err := (&Location{}).LocalTime(); !errors.Is(err, ErrUnknownTimeZone) {
+		t.Errorf("expected ErrUnknownTimeZone, got %v", err)
+	}
+	if _, err := (&Location{}).InBusinessHours(DefaultSchedule); err == nil {
+		t.Error("expected error without a time zone")
+	}
+	always := Schedule{{Days: []time.Weekday{0, 1, 2, 3, 4, 5, 6}, Open: 0, Close: 0}}
+	if open, err := loc.InBusinessHours(always); err != nil || !open {
+		t.Errorf("expected a 24h schedule to be open, got %v, %v", open, err)
+	}
+}
+
+func TestSchedule_Contains(t *testing.T) {
+	tz, _ := LoadTimeZone("Europe/Sofia")
+	at := func(day, hour, minute int) time.Time {
+		// 2025-06-02 is a Monday.
+		return time.Date(2025, 6, 2+day, hour, minute, 0, 0, tz)
+	}
+	night := BusinessHours{Days: []time.Weekday{time.Friday}, Open: 22 * time.Hour, Close: 6 * time.Hour}
+	support := Schedule{
+		{Open: 9 * time.Hour, Close: 17*time.Hour + 30*time.Minute},
+		{Days: []time.Weekday{time.Saturday}, Open: 10 * time.Hour, Close: 14 * time.Hour},
+		night,
+	}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		t        time.Time
+		want     bool
+	}{
+		{"monday morning", DefaultSchedule, at(0, 9, 0), true},
+		{"before opening", DefaultSchedule, at(0, 8, 59), false},
+		{"at closing", DefaultSchedule, at(0, 17, 0), false},
+		{"saturday", DefaultSchedule, at(5, 11, 0), false},
+		{"half past five", support, at(1, 17, 29), true},
+		{"saturday window", support, at(5, 11, 0), true},
+		{"sunday", support, at(6, 11, 0), false},
+		{"friday night", support, at(4, 23, 0), true},
+		{"after midnight", support, at(5, 5, 59), true},
+		{"morning after", support, at(5, 6, 0), false},
+		{"thursday night", support, at(3, 23, 0), false},
+		{"empty schedule", nil, at(0, 12, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.schedule.Contains(tt.t); got != tt.want {
+			t.Errorf("%s: Contains(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+
+	// The same instant is outside hours in Sofia but inside in New York.
+	ny, _ := LoadTimeZone("America/New_York")
+	instant := time.Date(2025, 6, 2, 20, 0, 0, 0, tz)
+	if DefaultSchedule.Contains(instant) || !DefaultSchedule.Contains(instant.In(ny)) {
+		t.Error("expected business hours to depend on the time zone")
+	}
+}
+
+func TestSimulation_TimeZone(t *testing.T) {
+	if got := FakeCloudflareHeaders("JP", nil)["CF-Timezone"]; got != "Asia/Tokyo" {
+		t.Errorf("expected simulated CF-Timezone, got %q", got)
+	}
+	if got := FromRequest(SimulateRequest("AU", nil)).TimeZone; got != "Australia/Sydney" {
+		t.Errorf("expected simulated time zone, got %q", got)
+	}
+}
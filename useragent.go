@@ -0,0 +1,171 @@
+package geolocation
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mssola/user_agent"
+)
+
+// parseUserAgent parses a raw User-Agent string into a ClientInfo.
+func parseUserAgent(userAgent string) *ClientInfo {
+	ua := user_agent.New(userAgent)
+	name, version := ua.Browser()
+	device := "Desktop"
+	lower := strings.ToLower(userAgent)
+	if ua.Mobile() {
+		device = "Mobile"
+	} else if strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") {
+		device = "Tablet"
+	}
+	osName, osVersion := normalizeOS(ua.OSInfo())
+	engine, engineVersion := detectEngine(ua, userAgent, osName)
+	app := detectInAppBrowser(userAgent)
+	return &ClientInfo{
+		BrowserName:    name,
+		BrowserVersion: version,
+		OS:             ua.OS(),
+		OSName:         osName,
+		OSVersion:      osVersion,
+		Engine:         engine,
+		EngineVersion:  engineVersion,
+		Device:         device,
+		InAppBrowser:   app != "",
+		InAppName:      app,
+		WebView:        isWebView(userAgent, osName),
+	}
+}
+
+// inAppMarkers maps User-Agent markers to the host application that embeds the browser.
+// Order matters: more specific markers are checked first.
+var inAppMarkers = []struct {
+	marker string
+	app    string
+}{
+	{"FBAN/Messenger", "Messenger"},
+	{"MessengerForiOS", "Messenger"},
+	{"Instagram", "Instagram"},
+	{"FBAN", "Facebook"},
+	{"FBAV", "Facebook"},
+	{"FB_IAB", "Facebook"},
+	{"musical_ly", "TikTok"},
+	{"BytedanceWebview", "TikTok"},
+	{"TikTok", "TikTok"},
+	{"Line/", "LINE"},
+	{"Snapchat", "Snapchat"},
+	{"Twitter for ", "Twitter"}, // iOS, e.g. "Twitter for iPhone"; not the Twitterbot crawler
+	{"TwitterAndroid", "Twitter"},
+	{"LinkedInApp", "LinkedIn"},
+	{"Pinterest/", "Pinterest"}, // e.g. "[Pinterest/iOS]"; not the Pinterestbot crawler
+	{"MicroMessenger", "WeChat"},
+}
+
+// detectInAppBrowser returns the name of the host app for in-app browsers, or empty string.
+func detectInAppBrowser(userAgent string) string {
+	for _, m := range inAppMarkers {
+		if strings.Contains(userAgent, m.marker) {
+			return m.app
+		}
+	}
+	return ""
+}
+
+// isWebView reports whether the User-Agent belongs to an embedded WebView
+// (Android "; wv)" marker, or an iOS WebKit view that does not identify as Safari).
+func isWebView(userAgent, osName string) bool {
+	if strings.Contains(userAgent, "; wv)") {
+		return true
+	}
+	return osName == "iOS" &&
+		strings.Contains(userAgent, "AppleWebKit/") &&
+		strings.Contains(userAgent, "Mobile/") &&
+		!strings.Contains(userAgent, "Safari/")
+}
+
+// IsEmbedded reports whether the client is an in-app browser or a WebView.
+func (c *ClientInfo) IsEmbedded() bool {
+	return c.InAppBrowser || c.WebView
+}
+
+// BrowserMajorVersion returns the major component of BrowserVersion (e.g., 123 for "123.0.0.0").
+// Returns 0 if the version is missing or not numeric.
+func (c *ClientInfo) BrowserMajorVersion() int {
+	return majorVersion(c.BrowserVersion)
+}
+
+// normalizeOS maps the OS details reported by the user agent parser to a stable
+// name (Windows, macOS, iOS, Android, ChromeOS, Linux, ...) and a dotted version.
+func normalizeOS(info user_agent.OSInfo) (string, string) {
+	name, version := info.Name, info.Version
+	switch {
+	case name == "":
+		return "", ""
+	case strings.Contains(info.FullName, "like Mac OS X"):
+		// iPhone, iPad and iPod all report "like Mac OS X"
+		return "iOS", version
+	case strings.HasPrefix(name, "Mac OS"):
+		return "macOS", version
+	case strings.HasPrefix(name, "Windows Phone"):
+		return "Windows Phone", version
+	case strings.HasPrefix(name, "Windows"):
+		return "Windows", strings.TrimPrefix(strings.TrimPrefix(info.FullName, "Windows NT "), "Windows ")
+	case strings.HasPrefix(name, "CrOS"):
+		return "ChromeOS", version
+	case name == "Android":
+		return "Android", version
+	}
+	return name, version
+}
+
+// detectEngine returns the rendering engine and its version.
+// Chromium-based browsers are reported as Blink, and every browser on iOS as WebKit.
+func detectEngine(ua *user_agent.UserAgent, userAgent, osName string) (string, string) {
+	engine, version := ua.Engine()
+	switch engine {
+	case "Gecko":
+		if rv := tokenVersion(userAgent, "rv:"); rv != "" {
+			version = rv
+		}
+		return "Gecko", version
+	case "Trident":
+		return "Trident", tokenVersion(userAgent, "Trident/")
+	case "Presto":
+		return "Presto", version
+	case "AppleWebKit":
+		if osName != "iOS" {
+			if v := tokenVersion(userAgent, "Edge/"); v != "" {
+				return "EdgeHTML", v
+			}
+			if v := tokenVersion(userAgent, "Chrome/"); v != "" && majorVersion(v) >= 28 {
+				return "Blink", v
+			}
+		}
+		return "WebKit", version
+	}
+	return engine, version
+}
+
+// tokenVersion returns the version that immediately follows token in the user agent,
+// e.g. tokenVersion(ua, "Chrome/") -> "123.0.0.0".
+func tokenVersion(userAgent, token string) string {
+	i := strings.Index(userAgent, token)
+	if i < 0 {
+		return ""
+	}
+	rest := userAgent[i+len(token):]
+	end := 0
+	for end < len(rest) && (rest[end] == '.' || (rest[end] >= '0' && rest[end] <= '9')) {
+		end++
+	}
+	return strings.TrimRight(rest[:end], ".")
+}
+
+// majorVersion parses the leading numeric component of a dotted version string.
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
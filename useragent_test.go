@@ -0,0 +1,254 @@
+package geolocation
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseClientInfo_NormalizedOSAndEngine(t *testing.T) {
+	tests := []struct {
+		name          string
+		ua            string
+		osName        string
+		osVersion     string
+		engine        string
+		engineVersion string
+		major         int
+	}{
+		{
+			name:          "Chrome on Windows",
+			ua:            "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36",
+			osName:        "Windows",
+			osVersion:     "10",
+			engine:        "Blink",
+			engineVersion: "123.0.0.0",
+			major:         123,
+		},
+		{
+			name:          "Safari on macOS",
+			ua:            "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Safari/605.1.15",
+			osName:        "macOS",
+			osVersion:     "10.15.7",
+			engine:        "WebKit",
+			engineVersion: "605.1.15",
+			major:         14,
+		},
+		{
+			name:          "Safari on iPhone",
+			ua:            "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			osName:        "iOS",
+			osVersion:     "17.2",
+			engine:        "WebKit",
+			engineVersion: "605.1.15",
+			major:         17,
+		},
+		{
+			name:          "Chrome on iPhone uses WebKit",
+			ua:            "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			osName:        "iOS",
+			osVersion:     "17.2",
+			engine:        "WebKit",
+			engineVersion: "605.1.15",
+			major:         120,
+		},
+		{
+			name:          "Safari on iPad",
+			ua:            "Mozilla/5.0 (iPad; CPU OS 14_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.1 Mobile/15E148 Safari/604.1",
+			osName:        "iOS",
+			osVersion:     "14.6",
+			engine:        "WebKit",
+			engineVersion: "605.1.15",
+			major:         14,
+		},
+		{
+			name:          "Chrome on Android",
+			ua:            "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+			osName:        "Android",
+			osVersion:     "14",
+			engine:        "Blink",
+			engineVersion: "120.0.0.0",
+			major:         120,
+		},
+		{
+			name:          "Firefox on Windows",
+			ua:            "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:89.0) Gecko/20100101 Firefox/89.0",
+			osName:        "Windows",
+			osVersion:     "10",
+			engine:        "Gecko",
+			engineVersion: "89.0",
+			major:         89,
+		},
+		{
+			name:          "Chrome on ChromeOS",
+			ua:            "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36",
+			osName:        "ChromeOS",
+			osVersion:     "14541.0.0",
+			engine:        "Blink",
+			engineVersion: "119.0.0.0",
+			major:         119,
+		},
+		{
+			name:          "Internet Explorer 11",
+			ua:            "Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			osName:        "Windows",
+			osVersion:     "7",
+			engine:        "Trident",
+			engineVersion: "7.0",
+			major:         11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/", nil)
+			r.Header.Set("User-Agent", tt.ua)
+			info := ParseClientInfo(r)
+			if info.OSName != tt.osName || info.OSVersion != tt.osVersion {
+				t.Errorf("expected OS %q %q, got %q %q", tt.osName, tt.osVersion, info.OSName, info.OSVersion)
+			}
+			if info.Engine != tt.engine || info.EngineVersion != tt.engineVersion {
+				t.Errorf("expected engine %q %q, got %q %q", tt.engine, tt.engineVersion, info.Engine, info.EngineVersion)
+			}
+			if got := info.BrowserMajorVersion(); got != tt.major {
+				t.Errorf("expected major version %d, got %d", tt.major, got)
+			}
+			if info.OS == "" {
+				t.Error("expected raw OS to be preserved")
+			}
+		})
+	}
+}
+
+func TestBrowserMajorVersion_Invalid(t *testing.T) {
+	for _, v := range []string{"", "abc", ".1"} {
+		info := &ClientInfo{BrowserVersion: v}
+		if got := info.BrowserMajorVersion(); got != 0 {
+			t.Errorf("expected 0 for version %q, got %d", v, got)
+		}
+	}
+}
+
+func TestGetGeoInfo_NormalizedFields(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36")
+	info := GetGeoInfo(r)
+	if info.OSName != "Android" || info.OSVersion != "14" || info.Engine != "Blink" || info.EngineVersion != "120.0.0.0" {
+		t.Errorf("unexpected normalized fields: %+v", info)
+	}
+}
+
+func TestTokenVersion(t *testing.T) {
+	if got := tokenVersion("Foo/1.2.3 Bar", "Foo/"); got != "1.2.3" {
+		t.Errorf("expected '1.2.3', got %q", got)
+	}
+	if got := tokenVersion("Foo/1.2. Bar", "Foo/"); got != "1.2" {
+		t.Errorf("expected trailing dot to be trimmed, got %q", got)
+	}
+	if got := tokenVersion("Bar", "Foo/"); got != "" {
+		t.Errorf("expected empty version for missing token, got %q", got)
+	}
+}
+
+func TestParseClientInfo_InAppBrowser(t *testing.T) {
+	tests := []struct {
+		name    string
+		ua      string
+		inApp   bool
+		app     string
+		webView bool
+	}{
+		{
+			name:    "Facebook on iOS",
+			ua:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/442.0.0.0;FBBV/1;FBDV/iPhone14,5;FBMD/iPhone;FBSN/iOS;FBSV/17.2;FBSS/3;FBID/phone;FBLC/en_US;FBOP/5]",
+			inApp:   true,
+			app:     "Facebook",
+			webView: true,
+		},
+		{
+			name:    "Instagram on Android",
+			ua:      "Mozilla/5.0 (Linux; Android 13; SM-G991B Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/119.0.6045.163 Mobile Safari/537.36 Instagram 307.0.0.34.111 Android (33/13; 420dpi; 1080x2176; samsung; SM-G991B; o1s; exynos2100; en_US; 532277008)",
+			inApp:   true,
+			app:     "Instagram",
+			webView: true,
+		},
+		{
+			name:    "TikTok on Android",
+			ua:      "Mozilla/5.0 (Linux; Android 12; Pixel 6 Build/SD1A.210817.036; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36 trill_290105 JsSdk/1.0 NetType/WIFI Channel/googleplay AppName/musical_ly app_version/29.1.5 ByteLocale/en",
+			inApp:   true,
+			app:     "TikTok",
+			webView: true,
+		},
+		{
+			name:    "LINE on iOS",
+			ua:      "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Safari Line/13.16.0",
+			inApp:   true,
+			app:     "LINE",
+			webView: true,
+		},
+		{
+			name:    "Twitter on iOS",
+			ua:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Twitter for iPhone/10.20",
+			inApp:   true,
+			app:     "Twitter",
+			webView: true,
+		},
+		{
+			name:    "Twitter on Android",
+			ua:      "Mozilla/5.0 (Linux; Android 13; Pixel 7 Build/TQ3A.230805.001; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/116.0.0.0 Mobile Safari/537.36 TwitterAndroid",
+			inApp:   true,
+			app:     "Twitter",
+			webView: true,
+		},
+		{
+			name: "Twitterbot crawler",
+			ua:   "Twitterbot/1.0",
+		},
+		{
+			name:    "Pinterest on iOS",
+			ua:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [Pinterest/iOS]",
+			inApp:   true,
+			app:     "Pinterest",
+			webView: true,
+		},
+		{
+			name: "Pinterestbot crawler",
+			ua:   "Mozilla/5.0 (compatible; Pinterestbot/1.0; +http://www.pinterest.com/bot.html)",
+		},
+		{
+			name:    "Plain Android WebView",
+			ua:      "Mozilla/5.0 (Linux; Android 14; Pixel 8; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/120.0.0.0 Mobile Safari/537.36",
+			webView: true,
+		},
+		{
+			name: "Mobile Safari",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+		},
+		{
+			name: "Desktop Chrome on Linux",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "/", nil)
+			r.Header.Set("User-Agent", tt.ua)
+			info := ParseClientInfo(r)
+			if info.InAppBrowser != tt.inApp || info.InAppName != tt.app || info.WebView != tt.webView {
+				t.Errorf("expected inApp=%v app=%q webView=%v, got %+v", tt.inApp, tt.app, tt.webView, info)
+			}
+			if IsInAppBrowser(r) != (tt.inApp || tt.webView) {
+				t.Errorf("unexpected IsInAppBrowser result for %q", tt.ua)
+			}
+		})
+	}
+}
+
+func TestGetGeoInfo_InAppBrowser(t *testing.T) {
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/442.0.0.0]")
+	info := GetGeoInfo(r)
+	if !info.InAppBrowser || info.InAppName != "Facebook" || !info.WebView {
+		t.Errorf("unexpected in-app fields: %+v", info)
+	}
+}